@@ -0,0 +1,305 @@
+package config
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/smallbiznis/go-lib/pkg/validator"
+	"go.uber.org/fx"
+	"gopkg.in/yaml.v3"
+)
+
+// Struct tags understood by the loader:
+//
+//	env:"GRPC_PORT"       environment variable holding the value
+//	default:":4317"       value used when the variable is unset
+//	required:"true"       fail when the value is unset or empty
+//	prefix:"KEEPALIVE_"   prefix added to the variables of a nested struct
+//	sep:";"               separator of slice and map entries, "," by default
+//	validate:"..."        validated with pkg/validator after loading
+//	secret:"true"         masked in the Report
+//
+// Every variable NAME can also be read from the file named by NAME_FILE,
+// as used for Docker and Kubernetes secrets.
+//
+// Values are resolved from the environment first, then from the file given
+// to WithFile and finally from the default. File keys are the lowercased
+// variable names without prefix, e.g. grpc_port, and nested structs are
+// nested mappings keyed by their lowercased field name. A yaml or json tag
+// overrides the key.
+const (
+	tagEnv      = "env"
+	tagDefault  = "default"
+	tagRequired = "required"
+	tagPrefix   = "prefix"
+	tagSep      = "sep"
+	tagSecret   = "secret"
+	fileSuffix  = "_FILE"
+)
+
+type loader struct {
+	prefix   string
+	dotenv   []string
+	file     string
+	root     *yaml.Node
+	lookup   func(string) (string, bool)
+	validate bool
+	report   *Report
+
+	dotenvValues map[string]string
+	values       []Value
+}
+
+type Option func(*loader)
+
+// WithPrefix prefixes every variable name, e.g. BILLING_ for BILLING_PORT.
+func WithPrefix(prefix string) Option {
+	return func(l *loader) {
+		l.prefix = prefix
+	}
+}
+
+// WithDotEnv reads variables from .env files. Variables set in the
+// environment take precedence, missing files are ignored.
+func WithDotEnv(files ...string) Option {
+	return func(l *loader) {
+		l.dotenv = append(l.dotenv, files...)
+	}
+}
+
+// WithFile reads values missing from the environment from a YAML or JSON
+// file.
+func WithFile(file string) Option {
+	return func(l *loader) {
+		l.file = file
+	}
+}
+
+// WithLookup replaces os.LookupEnv, e.g. in tests.
+func WithLookup(lookup func(string) (string, bool)) Option {
+	return func(l *loader) {
+		l.lookup = lookup
+	}
+}
+
+// WithReport records the resolved values in r instead of DefaultReport.
+func WithReport(r *Report) Option {
+	return func(l *loader) {
+		l.report = r
+	}
+}
+
+// WithoutValidation skips the validate tags.
+func WithoutValidation() Option {
+	return func(l *loader) {
+		l.validate = false
+	}
+}
+
+func newLoader(opts []Option) (*loader, error) {
+	l := &loader{
+		lookup:   os.LookupEnv,
+		validate: true,
+		report:   defaultReport,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	l.dotenvValues = make(map[string]string)
+	for _, file := range l.dotenv {
+		if err := readDotEnv(file, l.dotenvValues); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.file != "" {
+		root, err := readFile(l.file)
+		if err != nil {
+			return nil, err
+		}
+		l.root = root
+	}
+
+	return l, nil
+}
+
+// Load populates a new T from the environment.
+func Load[T any](opts ...Option) (*T, error) {
+	cfg := new(T)
+	if err := Populate(cfg, opts...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Populate fills the struct pointed to by dst from the environment.
+func Populate(dst any, opts ...Option) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: expected a pointer to a struct, got %T", dst)
+	}
+
+	l, err := newLoader(opts)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	l.populate(rv.Elem(), l.prefix, "", l.root, &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", stderrors.Join(errs...))
+	}
+
+	if l.validate {
+		if err := validator.NewValidator().Struct(dst); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+
+	if l.report != nil {
+		name := rv.Elem().Type().String()
+		if l.prefix != "" {
+			name += " (" + l.prefix + ")"
+		}
+		l.report.record(name, l.values)
+	}
+	return nil
+}
+
+// Provide loads T once and provides *T to the fx graph.
+func Provide[T any](opts ...Option) fx.Option {
+	return fx.Provide(func() (*T, error) {
+		return Load[T](opts...)
+	})
+}
+
+func (l *loader) populate(v reflect.Value, prefix, path string, node *yaml.Node, errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+
+		name, hasEnv := sf.Tag.Lookup(tagEnv)
+		if !hasEnv {
+			if isNested(sf.Type) {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						fv.Set(reflect.New(sf.Type.Elem()))
+					}
+					fv = fv.Elem()
+				}
+				l.populate(fv, prefix+sf.Tag.Get(tagPrefix), path+sf.Name+".", child(node, fileKey(sf, sf.Name)), errs)
+			}
+			continue
+		}
+
+		sep := sf.Tag.Get(tagSep)
+		if sep == "" {
+			sep = ","
+		}
+
+		key := prefix + name
+		value := Value{
+			Field:  path + sf.Name,
+			Key:    key,
+			Secret: sf.Tag.Get(tagSecret) == "true",
+		}
+
+		required := sf.Tag.Get(tagRequired) == "true"
+
+		raw, source, ok, err := l.value(key)
+		if err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+		// an empty required value counts as missing
+		ok = ok && !(required && raw == "")
+		if !ok {
+			if n := child(node, fileKey(sf, name)); n != nil && !(required && isEmptyNode(n)) {
+				if err := decodeNode(fv, n, sep); err != nil {
+					*errs = append(*errs, fmt.Errorf("invalid %s in %s: %w", fileKey(sf, name), l.file, redact(err, fv, value.Secret)))
+					continue
+				}
+				value.Value, value.Source = format(fv), SourceFile
+				l.values = append(l.values, value)
+				continue
+			}
+			raw, ok = sf.Tag.Lookup(tagDefault)
+			ok = ok && !(required && raw == "")
+			source = SourceDefault
+		}
+		if !ok {
+			if required {
+				*errs = append(*errs, fmt.Errorf("%s is required", key))
+				continue
+			}
+			value.Value, value.Source = format(fv), SourceUnset
+			l.values = append(l.values, value)
+			continue
+		}
+
+		if err := setValue(fv, raw, sep); err != nil {
+			*errs = append(*errs, fmt.Errorf("invalid %s: %w", key, redact(err, fv, value.Secret)))
+			continue
+		}
+		value.Value, value.Source = raw, source
+		l.values = append(l.values, value)
+	}
+}
+
+// redact replaces the parse error of a secret field, which may quote the
+// value, with one naming only the expected type.
+func redact(err error, v reflect.Value, secret bool) error {
+	if !secret {
+		return err
+	}
+	return fmt.Errorf("value is not a valid %s", v.Type())
+}
+
+// value reads key from the environment, the .env files, or from the file
+// named by key_FILE.
+func (l *loader) value(key string) (string, Source, bool, error) {
+	if v, source, ok := l.env(key); ok {
+		return v, source, true, nil
+	}
+
+	file, _, ok := l.env(key + fileSuffix)
+	if !ok || file == "" {
+		return "", "", false, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", false, fmt.Errorf("read %s%s: %w", key, fileSuffix, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), SourceSecretFile, true, nil
+}
+
+func (l *loader) env(key string) (string, Source, bool) {
+	if v, ok := l.lookup(key); ok {
+		return v, SourceEnv, true
+	}
+	if v, ok := l.dotenvValues[key]; ok {
+		return v, SourceDotEnv, true
+	}
+	return "", "", false
+}
+
+// isNested reports whether t is a struct the loader descends into rather
+// than a value it decodes.
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	return !isScalar(t)
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+type keepalive struct {
+	Time    time.Duration `env:"TIME" default:"2h"`
+	Timeout time.Duration `env:"TIMEOUT"`
+}
+
+type testConfig struct {
+	Addr      string            `env:"GRPC_PORT" default:":4317"`
+	Workers   int               `env:"WORKERS" default:"4" validate:"gte=1"`
+	Debug     bool              `env:"DEBUG"`
+	Hosts     []string          `env:"HOSTS"`
+	Ports     []int             `env:"PORTS" sep:";"`
+	Labels    map[string]string `env:"LABELS"`
+	Endpoint  *url.URL          `env:"ENDPOINT"`
+	Password  string            `env:"PASSWORD" required:"true"`
+	Keepalive keepalive         `prefix:"KEEPALIVE_"`
+	ignored   string
+}
+
+func lookup(values map[string]string) Option {
+	return WithLookup(func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	})
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load[testConfig](lookup(map[string]string{
+		"WORKERS":           "8",
+		"DEBUG":             "true",
+		"HOSTS":             "a, b",
+		"PORTS":             "80;443",
+		"LABELS":            "team=billing,tier=1",
+		"ENDPOINT":          "https://api.example.com/v1",
+		"PASSWORD":          "secret",
+		"KEEPALIVE_TIMEOUT": "20s",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Addr != ":4317" || cfg.Workers != 8 || !cfg.Debug {
+		t.Errorf("unexpected scalars %+v", cfg)
+	}
+	if strings.Join(cfg.Hosts, "|") != "a|b" || len(cfg.Ports) != 2 || cfg.Ports[1] != 443 {
+		t.Errorf("unexpected slices %v %v", cfg.Hosts, cfg.Ports)
+	}
+	if cfg.Labels["team"] != "billing" || cfg.Labels["tier"] != "1" {
+		t.Errorf("unexpected labels %v", cfg.Labels)
+	}
+	if cfg.Endpoint == nil || cfg.Endpoint.Host != "api.example.com" {
+		t.Errorf("unexpected endpoint %v", cfg.Endpoint)
+	}
+	if cfg.Keepalive.Time != 2*time.Hour || cfg.Keepalive.Timeout != 20*time.Second {
+		t.Errorf("unexpected keepalive %+v", cfg.Keepalive)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load[testConfig](lookup(map[string]string{
+		"WORKERS": "many",
+		"DEBUG":   "maybe",
+	}))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"invalid WORKERS", "invalid DEBUG", "PASSWORD is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestLoadRequiredEmpty(t *testing.T) {
+	_, err := Load[testConfig](lookup(map[string]string{
+		"PASSWORD": "",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "PASSWORD is required") {
+		t.Errorf("expected required error, got %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("password: \"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load[testConfig](WithFile(file), lookup(map[string]string{}))
+	if err == nil || !strings.Contains(err.Error(), "PASSWORD is required") {
+		t.Errorf("expected required error for empty file value, got %v", err)
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	_, err := Load[testConfig](lookup(map[string]string{
+		"WORKERS":  "0",
+		"PASSWORD": "secret",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "Workers") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestLoadPrefix(t *testing.T) {
+	cfg, err := Load[testConfig](WithPrefix("BILLING_"), lookup(map[string]string{
+		"BILLING_GRPC_PORT":      ":9000",
+		"BILLING_PASSWORD":       "secret",
+		"BILLING_KEEPALIVE_TIME": "1m",
+		"PASSWORD":               "ignored",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9000" || cfg.Password != "secret" || cfg.Keepalive.Time != time.Minute {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
+
+func TestLoadFileSecret(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load[testConfig](lookup(map[string]string{
+		"PASSWORD_FILE": file,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "from-file" {
+		t.Errorf("unexpected password %q", cfg.Password)
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	content := `# local overrides
+export PASSWORD="dot\tenv"
+WORKERS=2 # inline comment
+HOSTS='x,y'
+`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load[testConfig](
+		WithDotEnv(file, filepath.Join(t.TempDir(), "missing.env")),
+		lookup(map[string]string{"WORKERS": "3"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "dot\tenv" || cfg.Workers != 3 || len(cfg.Hosts) != 2 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
+
+func TestProvide(t *testing.T) {
+	t.Setenv("PASSWORD", "secret")
+
+	var cfg *testConfig
+	app := fx.New(
+		Provide[testConfig](),
+		fx.Populate(&cfg),
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "secret" {
+		t.Errorf("unexpected password %q", cfg.Password)
+	}
+}
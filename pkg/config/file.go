@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile parses a YAML or JSON file, JSON being a subset of YAML, and
+// returns its top-level mapping.
+func readFile(file string) (*yaml.Node, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", file, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config: %s must contain a mapping", file)
+	}
+	return root, nil
+}
+
+// fileKey is the key of a field in the config file: its yaml or json tag,
+// or else the lowercased name.
+func fileKey(sf reflect.StructField, name string) string {
+	for _, tag := range []string{"yaml", "json"} {
+		if key, _, _ := strings.Cut(sf.Tag.Get(tag), ","); key != "" && key != "-" {
+			return key
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// child returns the value of key in the mapping node, ignoring case, or
+// nil when the key is missing or null.
+func child(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			if v := node.Content[i+1]; v.Tag != "!!null" {
+				return v
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// decodeNode decodes a file value into v. Scalars go through setValue so
+// file and environment values share the same syntax.
+func decodeNode(v reflect.Value, node *yaml.Node, sep string) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch {
+	case node.Kind == yaml.ScalarNode:
+		return setValue(v, node.Value, sep)
+	case v.Kind() == reflect.Pointer:
+		ptr := reflect.New(v.Type().Elem())
+		if err := decodeNode(ptr.Elem(), node, sep); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	case node.Kind == yaml.SequenceNode && v.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), len(node.Content), len(node.Content))
+		for i, item := range node.Content {
+			if err := decodeNode(s.Index(i), item, sep); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case node.Kind == yaml.MappingNode && v.Kind() == reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			kv := reflect.New(v.Type().Key()).Elem()
+			if err := decodeNode(kv, node.Content[i], sep); err != nil {
+				return err
+			}
+			vv := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeNode(vv, node.Content[i+1], sep); err != nil {
+				return err
+			}
+			m.SetMapIndex(kv, vv)
+		}
+		v.Set(m)
+		return nil
+	}
+	return node.Decode(v.Addr().Interface())
+}
+
+// isEmptyNode reports whether n is an empty or null scalar.
+func isEmptyNode(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && (n.Value == "" || n.Tag == "!!null")
+}
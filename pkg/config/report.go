@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Module provides DefaultReport and logs it once the application starts.
+var Module = fx.Module("config", fx.Options(
+	fx.Provide(DefaultReport),
+	fx.Invoke(logReport),
+))
+
+// Source is where a config value was resolved from.
+type Source string
+
+const (
+	SourceDefault    Source = "default"
+	SourceEnv        Source = "env"
+	SourceDotEnv     Source = "dotenv"
+	SourceFile       Source = "file"
+	SourceSecretFile Source = "secret_file"
+	SourceUnset      Source = "unset"
+)
+
+// redacted replaces the value of fields tagged secret:"true".
+const redacted = "******"
+
+// Value is a resolved config field.
+type Value struct {
+	// Field is the Go path of the field, e.g. Keepalive.Time.
+	Field string `json:"field"`
+	// Key is the environment variable of the field.
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Section holds the values of one loaded config struct.
+type Section struct {
+	Name     string    `json:"name"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Values   []Value   `json:"values"`
+}
+
+// Report keeps the values resolved by the latest successful load of every
+// config struct, with secrets masked. It serves them as JSON, e.g. on an
+// admin router:
+//
+//	admin.GET("/config", gin.WrapH(config.DefaultReport()))
+type Report struct {
+	mu       sync.RWMutex
+	sections map[string]Section
+}
+
+func NewReport() *Report {
+	return &Report{
+		sections: make(map[string]Section),
+	}
+}
+
+var defaultReport = NewReport()
+
+// DefaultReport is the Report every Load records to unless WithReport is
+// given.
+func DefaultReport() *Report {
+	return defaultReport
+}
+
+func (r *Report) record(name string, values []Value) {
+	masked := make([]Value, len(values))
+	for i, v := range values {
+		if v.Secret && v.Value != "" {
+			v.Value = redacted
+		}
+		masked[i] = v
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sections[name] = Section{
+		Name:     name,
+		LoadedAt: time.Now(),
+		Values:   masked,
+	}
+}
+
+// Sections returns the recorded configs ordered by name.
+func (r *Report) Sections() []Section {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sections := make([]Section, 0, len(r.sections))
+	for _, s := range r.sections {
+		sections = append(sections, s)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].Name < sections[j].Name
+	})
+	return sections
+}
+
+// Log writes every recorded config to log.
+func (r *Report) Log(log *zap.Logger) {
+	for _, s := range r.Sections() {
+		fields := make([]zap.Field, 0, len(s.Values)+1)
+		fields = append(fields, zap.String("config", s.Name))
+		for _, v := range s.Values {
+			fields = append(fields, zap.String(v.Key, fmt.Sprintf("%s (%s)", v.Value, v.Source)))
+		}
+		log.Info("config resolved", fields...)
+	}
+}
+
+func (r *Report) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(r.Sections())
+}
+
+func logReport(lc fx.Lifecycle, log *zap.Logger) {
+	lc.Append(fx.StartHook(func() {
+		defaultReport.Log(log)
+	}))
+}
+
+// format renders a decoded field for the Report.
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if u, ok := v.Interface().(url.URL); ok {
+		return u.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
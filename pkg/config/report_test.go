@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type reportConfig struct {
+	Addr     string `env:"ADDR" default:":8080"`
+	Level    string `env:"LEVEL"`
+	Region   string `env:"REGION"`
+	Mode     string `env:"MODE"`
+	Token    string `env:"TOKEN" secret:"true"`
+	Password string `env:"PASSWORD" secret:"true"`
+	Unused   int    `env:"UNUSED"`
+}
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+	writeFile(t, secret, "hunter2")
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "region: eu-west-1\n")
+	dotenv := filepath.Join(dir, ".env")
+	writeFile(t, dotenv, "MODE=local\n")
+
+	report := NewReport()
+	_, err := Load[reportConfig](
+		WithReport(report),
+		WithFile(file),
+		WithDotEnv(dotenv),
+		lookup(map[string]string{
+			"LEVEL":         "debug",
+			"TOKEN":         "sk_live_123",
+			"PASSWORD_FILE": secret,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sections := report.Sections()
+	if len(sections) != 1 || sections[0].Name != "config.reportConfig" {
+		t.Fatalf("unexpected sections %+v", sections)
+	}
+
+	want := map[string]Value{
+		"ADDR":     {Value: ":8080", Source: SourceDefault},
+		"LEVEL":    {Value: "debug", Source: SourceEnv},
+		"REGION":   {Value: "eu-west-1", Source: SourceFile},
+		"MODE":     {Value: "local", Source: SourceDotEnv},
+		"TOKEN":    {Value: redacted, Source: SourceEnv},
+		"PASSWORD": {Value: redacted, Source: SourceSecretFile},
+		"UNUSED":   {Value: "0", Source: SourceUnset},
+	}
+	for _, v := range sections[0].Values {
+		w, ok := want[v.Key]
+		if !ok || v.Value != w.Value || v.Source != w.Source {
+			t.Errorf("unexpected value %+v", v)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	report.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	var body []Section
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 1 || len(body[0].Values) != len(want) {
+		t.Errorf("unexpected body %s", rec.Body)
+	}
+
+	core, logs := observer.New(zap.InfoLevel)
+	report.Log(zap.New(core))
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("unexpected log entries %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["TOKEN"] != redacted+" (env)" || fields["REGION"] != "eu-west-1 (file)" {
+		t.Errorf("unexpected log fields %v", fields)
+	}
+}
+
+func TestReportKeepsLastSuccessfulLoad(t *testing.T) {
+	report := NewReport()
+	if _, err := Load[reportConfig](WithReport(report), lookup(map[string]string{"LEVEL": "info"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load[reportConfig](WithReport(report), lookup(map[string]string{"UNUSED": "x"})); err == nil {
+		t.Fatal("expected error")
+	}
+
+	for _, v := range report.Sections()[0].Values {
+		if v.Key == "LEVEL" && v.Value != "info" {
+			t.Errorf("unexpected level %+v", v)
+		}
+	}
+}
+
+func TestLoadRedactsSecretErrors(t *testing.T) {
+	type secretConfig struct {
+		Port  int `env:"PORT" secret:"true"`
+		Plain int `env:"PLAIN"`
+	}
+
+	_, err := Load[secretConfig](WithReport(NewReport()), lookup(map[string]string{
+		"PORT":  "sk_live_123",
+		"PLAIN": "abc",
+	}))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "sk_live_123") {
+		t.Errorf("secret value leaked in %q", err)
+	}
+	if !strings.Contains(err.Error(), "invalid PORT") || !strings.Contains(err.Error(), `"abc"`) {
+		t.Errorf("unexpected error %q", err)
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// WatchInterval is how often ProvideWatcher checks the file for changes.
+const WatchInterval = 5 * time.Second
+
+// Watcher holds the latest valid T loaded from the environment and a YAML
+// or JSON file, and reloads it when the file changes or the process
+// receives SIGHUP. A reload that fails to load or validate keeps the
+// previous value.
+//
+//	w.Subscribe(func(old, new Config) {
+//		level.SetLevel(new.LogLevel)
+//	})
+type Watcher[T any] struct {
+	file    string
+	opts    []Option
+	current atomic.Pointer[T]
+	stat    os.FileInfo
+
+	reload sync.Mutex
+
+	mu        sync.Mutex
+	subs      []subscription[T]
+	nextID    int
+	pending   []change[T]
+	notifying bool
+}
+
+type subscription[T any] struct {
+	id int
+	fn func(old, new T)
+}
+
+type change[T any] struct {
+	old, new *T
+}
+
+// NewWatcher loads T with opts and file, failing when the initial load
+// does.
+func NewWatcher[T any](file string, opts ...Option) (*Watcher[T], error) {
+	w := &Watcher[T]{
+		file: file,
+		opts: append(append([]Option{}, opts...), WithFile(file)),
+	}
+	w.stat, _ = os.Stat(file)
+
+	cfg, err := Load[T](w.opts...)
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(cfg)
+
+	return w, nil
+}
+
+// Get returns the current config.
+func (w *Watcher[T]) Get() T {
+	return *w.current.Load()
+}
+
+// Subscribe calls fn after every successful reload with the previous and
+// the new config. Calls are serialized. The returned func unsubscribes.
+func (w *Watcher[T]) Subscribe(fn func(old, new T)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.nextID++
+	id := w.nextID
+	w.subs = append(w.subs, subscription[T]{id: id, fn: fn})
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		for i, s := range w.subs {
+			if s.id == id {
+				w.subs = append(w.subs[:i:i], w.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Reload loads the config again and, when it is valid, swaps it in and
+// notifies subscribers. No lock is held while subscribers run, so they may
+// call Reload or Subscribe. When another Reload is already notifying, the
+// change is queued and delivered by it in order.
+func (w *Watcher[T]) Reload() error {
+	w.reload.Lock()
+	cfg, err := Load[T](w.opts...)
+	if err != nil {
+		w.reload.Unlock()
+		return err
+	}
+	old := w.current.Swap(cfg)
+
+	w.mu.Lock()
+	w.pending = append(w.pending, change[T]{old: old, new: cfg})
+	notify := !w.notifying
+	w.notifying = true
+	w.mu.Unlock()
+	w.reload.Unlock()
+
+	if notify {
+		w.notify()
+	}
+	return nil
+}
+
+// notify delivers queued changes until none are left.
+func (w *Watcher[T]) notify() {
+	for {
+		w.mu.Lock()
+		if len(w.pending) == 0 {
+			w.notifying = false
+			w.mu.Unlock()
+			return
+		}
+		c := w.pending[0]
+		w.pending = w.pending[1:]
+		subs := append([]subscription[T]{}, w.subs...)
+		w.mu.Unlock()
+
+		for _, s := range subs {
+			s.fn(*c.old, *c.new)
+		}
+	}
+}
+
+// Watch reloads the config on SIGHUP and whenever the file's size or
+// modification time changes, checked every interval, until ctx is done.
+// Reload errors are passed to onError when it is not nil.
+func (w *Watcher[T]) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+		}
+
+		if err := w.Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// changed reports whether the file differs from the last time it was
+// checked. A missing file counts as unchanged so a file being replaced is
+// picked up once it exists again.
+func (w *Watcher[T]) changed() bool {
+	stat, err := os.Stat(w.file)
+	if err != nil {
+		return false
+	}
+
+	prev := w.stat
+	w.stat = stat
+	return prev == nil || !stat.ModTime().Equal(prev.ModTime()) || stat.Size() != prev.Size()
+}
+
+type watcherParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Log       *zap.Logger `optional:"true"`
+}
+
+// ProvideWatcher provides a *Watcher[T] for file that watches for changes
+// while the application runs. Failed reloads are logged when a
+// *zap.Logger is available.
+func ProvideWatcher[T any](file string, opts ...Option) fx.Option {
+	return fx.Provide(func(p watcherParams) (*Watcher[T], error) {
+		w, err := NewWatcher[T](file, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		onError := func(err error) {
+			if p.Log != nil {
+				p.Log.Warn("config reload failed, keeping previous config", zap.String("file", file), zap.Error(err))
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		p.Lifecycle.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				go func() {
+					defer close(done)
+					w.Watch(ctx, WatchInterval, onError)
+				}()
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				cancel()
+				<-done
+				return nil
+			},
+		})
+
+		return w, nil
+	})
+}
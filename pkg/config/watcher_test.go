@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type reloadable struct {
+	LogLevel string   `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+	Excluded []string `env:"EXCLUDED" yaml:"excluded_endpoints"`
+	Limits   struct {
+		RPS time.Duration `env:"RPS"`
+	} `prefix:"LIMITS_"`
+}
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, `
+log_level: debug
+excluded_endpoints: [/metrics, /healthz]
+limits:
+  rps: 1s
+`)
+
+	cfg, err := Load[reloadable](WithFile(file), lookup(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogLevel != "debug" || strings.Join(cfg.Excluded, ",") != "/metrics,/healthz" || cfg.Limits.RPS != time.Second {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	cfg, err = Load[reloadable](WithFile(file), lookup(map[string]string{"LOG_LEVEL": "warn"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("expected environment to win over file, got %q", cfg.LogLevel)
+	}
+}
+
+func TestLoadJSONFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, file, `{"log_level": "error", "limits": {"rps": "2s"}}`)
+
+	cfg, err := Load[reloadable](WithFile(file), lookup(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogLevel != "error" || cfg.Limits.RPS != 2*time.Second {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, "log_level: info\n")
+
+	w, err := NewWatcher[reloadable](file, lookup(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls [][2]string
+	unsubscribe := w.Subscribe(func(old, new reloadable) {
+		calls = append(calls, [2]string{old.LogLevel, new.LogLevel})
+	})
+
+	writeFile(t, file, "log_level: debug\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Get().LogLevel != "debug" || len(calls) != 1 || calls[0] != [2]string{"info", "debug"} {
+		t.Errorf("unexpected reload %q %v", w.Get().LogLevel, calls)
+	}
+
+	writeFile(t, file, "log_level: verbose\n")
+	if err := w.Reload(); err == nil {
+		t.Error("expected validation error")
+	}
+	if w.Get().LogLevel != "debug" || len(calls) != 1 {
+		t.Errorf("expected previous config to be kept, got %q %v", w.Get().LogLevel, calls)
+	}
+
+	unsubscribe()
+	writeFile(t, file, "log_level: warn\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("unexpected call after unsubscribe %v", calls)
+	}
+}
+
+func TestWatcherReloadFromSubscriber(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, "log_level: info\n")
+
+	w, err := NewWatcher[reloadable](file, lookup(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	w.Subscribe(func(_, new reloadable) {
+		calls = append(calls, new.LogLevel)
+		if new.LogLevel == "debug" {
+			if err := os.WriteFile(file, []byte("log_level: warn\n"), 0o600); err != nil {
+				t.Error(err)
+			}
+			if err := w.Reload(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	writeFile(t, file, "log_level: debug\n")
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Reload()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Reload from a subscriber deadlocked")
+	}
+	if strings.Join(calls, ",") != "debug,warn" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
+
+func TestWatcherWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, "log_level: info\n")
+
+	w, err := NewWatcher[reloadable](file, lookup(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := make(chan string, 2)
+	w.Subscribe(func(_, new reloadable) {
+		reloaded <- new.LogLevel
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Watch(ctx, 10*time.Millisecond, func(err error) { t.Error(err) })
+
+	writeFile(t, file, "log_level: error\n")
+	select {
+	case level := <-reloaded:
+		if level != "error" {
+			t.Errorf("unexpected level %q", level)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("file change not picked up")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case level := <-reloaded:
+		if level != "error" {
+			t.Errorf("unexpected level %q", level)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGHUP not picked up")
+	}
+}
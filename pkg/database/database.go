@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/smallbiznis/go-lib/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var Module = fx.Module("database", fx.Options(
+	fx.Provide(
+		NewConfig,
+		provideDB,
+		SQLDB,
+	),
+))
+
+// Config configures the database opened by New.
+type Config struct {
+	// Driver is postgres, mysql or a driver added with Register, e.g. sqlite
+	// by importing pkg/database/sqlite.
+	Driver          string        `env:"DB_DRIVER" default:"postgres"`
+	DSN             string        `env:"DB_DSN" required:"true" secret:"true"`
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"25" validate:"gte=0"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"10" validate:"gte=0"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+	// SlowThreshold is the duration above which queries are logged as slow.
+	SlowThreshold time.Duration `env:"DB_SLOW_THRESHOLD" default:"200ms"`
+	// LogLevel is one of silent, error, warn or info.
+	LogLevel string `env:"DB_LOG_LEVEL" default:"warn" validate:"oneof=silent error warn info"`
+}
+
+// NewConfig reads the database configuration from the environment.
+func NewConfig() (*Config, error) {
+	return config.Load[Config]()
+}
+
+// New opens the database described by cfg, logging through log and
+// reporting spans and query durations through tp and mp.
+func New(cfg *Config, log *zap.Logger, tp trace.TracerProvider, mp metric.MeterProvider) (*gorm.DB, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("database dsn is required")
+	}
+
+	dialector, err := Dialector(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: NewLogger(log, cfg.SlowThreshold, ParseLogLevel(cfg.LogLevel)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plugin, err := NewTracing(cfg.Driver, tp, mp)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(plugin); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// SQLDB returns the *sql.DB of db, e.g. for health.ReadinessHandler.
+func SQLDB(db *gorm.DB) (*sql.DB, error) {
+	return db.DB()
+}
+
+type params struct {
+	fx.In
+
+	Lifecycle      fx.Lifecycle
+	Config         *Config
+	Logger         *zap.Logger              `optional:"true"`
+	TracerProvider *sdktrace.TracerProvider `optional:"true"`
+	MeterProvider  *sdkmetric.MeterProvider `optional:"true"`
+}
+
+// provideDB opens the database, falling back to the global logger and
+// OpenTelemetry providers when otelcol isn't used, and closes it on stop.
+func provideDB(p params) (*gorm.DB, error) {
+	log := p.Logger
+	if log == nil {
+		log = zap.L()
+	}
+
+	var tp trace.TracerProvider = otel.GetTracerProvider()
+	if p.TracerProvider != nil {
+		tp = p.TracerProvider
+	}
+
+	var mp metric.MeterProvider = otel.GetMeterProvider()
+	if p.MeterProvider != nil {
+		mp = p.MeterProvider
+	}
+
+	db, err := New(p.Config, log, tp, mp)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	})
+
+	return db, nil
+}
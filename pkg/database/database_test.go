@@ -0,0 +1,54 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/smallbiznis/go-lib/pkg/database"
+	_ "github.com/smallbiznis/go-lib/pkg/database/sqlite"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+func TestModule(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_DSN", "file::memory:?cache=shared")
+
+	var db *gorm.DB
+	app := fx.New(
+		database.Module,
+		fx.Populate(&db),
+		fx.Invoke(func(*sql.DB) {}),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var one int
+	if err := db.Raw("SELECT 1").Scan(&one).Error; err != nil || one != 1 {
+		t.Errorf("unexpected result %d: %v", one, err)
+	}
+
+	if err := app.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestModuleRequiresDSN(t *testing.T) {
+	t.Setenv("DB_DSN", "")
+
+	if err := fx.New(database.Module, fx.Invoke(func(*gorm.DB) {})).Err(); err == nil {
+		t.Error("expected missing dsn error")
+	}
+}
+
+func TestDialectorUnknownDriver(t *testing.T) {
+	if _, err := database.Dialector("oracle", "dsn"); err == nil {
+		t.Error("expected unsupported driver error")
+	}
+}
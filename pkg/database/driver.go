@@ -0,0 +1,43 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// OpenFunc builds the gorm dialector of a driver for dsn.
+type OpenFunc func(dsn string) gorm.Dialector
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]OpenFunc{
+		"postgres": postgres.Open,
+		"mysql":    mysql.Open,
+	}
+)
+
+// Register makes a driver available to Dialector under name. Drivers that
+// need cgo, like sqlite, live in their own package and register themselves
+// when imported:
+//
+//	import _ "github.com/smallbiznis/go-lib/pkg/database/sqlite"
+func Register(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = open
+}
+
+// Dialector returns the gorm dialector for driver.
+func Dialector(driver, dsn string) (gorm.Dialector, error) {
+	driversMu.RLock()
+	open, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	return open(dsn), nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type zapLogger struct {
+	log           *zap.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+var _ logger.Interface = new(zapLogger)
+
+// NewLogger returns a gorm logger writing to log. Queries slower than
+// slowThreshold are logged as warnings.
+func NewLogger(log *zap.Logger, slowThreshold time.Duration, level logger.LogLevel) logger.Interface {
+	return &zapLogger{
+		log:           log.WithOptions(zap.AddCallerSkip(3)),
+		level:         level,
+		slowThreshold: slowThreshold,
+	}
+}
+
+// ParseLogLevel maps silent, error, warn and info to gorm log levels.
+func ParseLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	}
+	return logger.Warn
+}
+
+func (l *zapLogger) LogMode(level logger.LogLevel) logger.Interface {
+	c := *l
+	c.level = level
+	return &c
+}
+
+func (l *zapLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.log.Info(fmt.Sprintf(msg, data...), traceFields(ctx)...)
+	}
+}
+
+func (l *zapLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log.Warn(fmt.Sprintf(msg, data...), traceFields(ctx)...)
+	}
+}
+
+func (l *zapLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.log.Error(fmt.Sprintf(msg, data...), traceFields(ctx)...)
+	}
+}
+
+func (l *zapLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	fields := append(traceFields(ctx),
+		zap.String("sql", sql),
+		zap.Int64("rows", rows),
+		zap.Duration("elapsed", elapsed),
+	)
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.log.Error("query failed", append(fields, zap.Error(err))...)
+	case l.slowThreshold != 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		l.log.Warn("slow query", append(fields, zap.Duration("slow_threshold", l.slowThreshold))...)
+	case l.level >= logger.Info:
+		l.log.Info("query", fields...)
+	}
+}
+
+func traceFields(ctx context.Context) []zapcore.Field {
+	fields := []zapcore.Field{}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields = append(fields, zap.String("trace_id", span.TraceID().String()))
+		fields = append(fields, zap.String("span_id", span.SpanID().String()))
+	}
+	return fields
+}
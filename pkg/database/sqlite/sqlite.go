@@ -0,0 +1,14 @@
+// Package sqlite registers the sqlite driver with pkg/database. It requires
+// cgo.
+//
+//	import _ "github.com/smallbiznis/go-lib/pkg/database/sqlite"
+package sqlite
+
+import (
+	"github.com/smallbiznis/go-lib/pkg/database"
+	"gorm.io/driver/sqlite"
+)
+
+func init() {
+	database.Register("sqlite", sqlite.Open)
+}
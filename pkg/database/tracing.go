@@ -0,0 +1,127 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	instrumentationName = "github.com/smallbiznis/go-lib/pkg/database"
+
+	spanKey  = "otel:span"
+	startKey = "otel:start"
+)
+
+// Tracing is a gorm plugin creating a span and recording the duration of
+// every statement.
+type Tracing struct {
+	system   string
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+}
+
+var _ gorm.Plugin = new(Tracing)
+
+// NewTracing builds the tracing plugin for the given database system.
+func NewTracing(system string, tp trace.TracerProvider, mp metric.MeterProvider) (*Tracing, error) {
+	duration, err := mp.Meter(instrumentationName).Float64Histogram(
+		"db.client.query.duration",
+		metric.WithDescription("Duration of database queries"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tracing{
+		system:   system,
+		tracer:   tp.Tracer(instrumentationName),
+		duration: duration,
+	}, nil
+}
+
+func (t *Tracing) Name() string {
+	return "otel"
+}
+
+func (t *Tracing) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	for _, r := range []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	} {
+		if err := r.before("otel:before_"+r.name, t.before(r.name)); err != nil {
+			return err
+		}
+		if err := r.after("otel:after_"+r.name, t.after(r.name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Tracing) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := t.tracer.Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(t.system),
+				semconv.DBOperationKey.String(operation),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+		db.InstanceSet(startKey, time.Now())
+	}
+}
+
+func (t *Tracing) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		attrs := []attribute.KeyValue{
+			semconv.DBSystemKey.String(t.system),
+			semconv.DBOperationKey.String(operation),
+			semconv.DBSQLTableKey.String(db.Statement.Table),
+		}
+
+		if start, ok := db.InstanceGet(startKey); ok {
+			elapsed := time.Since(start.(time.Time))
+			t.duration.Record(db.Statement.Context, elapsed.Seconds(), metric.WithAttributes(attrs...))
+		}
+
+		v, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span := v.(trace.Span)
+		defer span.End()
+
+		span.SetAttributes(attrs...)
+		span.SetAttributes(
+			semconv.DBStatementKey.String(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
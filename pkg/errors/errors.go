@@ -2,6 +2,8 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,63 +12,211 @@ type Error interface {
 	Error() string
 }
 
+// APIError is the error built by New and the status helpers. It carries the
+// HTTP status, name and details written to the response. Use From to get it
+// out of an error.
+type APIError interface {
+	error
+	Status() int
+	Name() string
+	Message() string
+	Details() []gin.H
+	Unwrap() error
+}
+
 type apiError struct {
-	Status  int     `json:"status"`
-	Name    string  `json:"name"`
-	Msg     string  `json:"message"`
-	Details []gin.H `json:"details"`
+	Code   int     `json:"status"`
+	Reason string  `json:"name"`
+	Msg    string  `json:"message"`
+	Detail []gin.H `json:"details"`
+
+	cause    error
+	sentinel bool
 }
 
 var _ Error = new(apiError)
+var _ APIError = new(apiError)
 var _ error = new(apiError)
 
+// Sentinels matching any error of the same status, e.g.
+// errors.Is(err, errors.ErrNotFound).
+var (
+	ErrBadRequest      = sentinel(http.StatusBadRequest, "BadRequest")
+	ErrUnauthorized    = sentinel(http.StatusUnauthorized, "Unauthorized")
+	ErrForbidden       = sentinel(http.StatusForbidden, "Forbidden")
+	ErrNotFound        = sentinel(http.StatusNotFound, "NotFound")
+	ErrConflict        = sentinel(http.StatusConflict, "Conflict")
+	ErrUnprocessable   = sentinel(http.StatusUnprocessableEntity, "Unprocessable")
+	ErrTooManyRequests = sentinel(http.StatusTooManyRequests, "TooManyRequests")
+	ErrInternal        = sentinel(http.StatusInternalServerError, "InternalServerError")
+	ErrUnavailable     = sentinel(http.StatusServiceUnavailable, "Unavailable")
+	ErrTimeout         = sentinel(http.StatusGatewayTimeout, "Timeout")
+)
+
+func sentinel(code int, name string) error {
+	return &apiError{
+		Code:     code,
+		Reason:   name,
+		Msg:      http.StatusText(code),
+		sentinel: true,
+	}
+}
+
 func (e *apiError) Error() string {
 	b, _ := json.Marshal(e)
 	return string(b)
 }
 
+// Status
+func (e *apiError) Status() int {
+	return e.Code
+}
+
+// Name
+func (e *apiError) Name() string {
+	return e.Reason
+}
+
+// Message
+func (e *apiError) Message() string {
+	return e.Msg
+}
+
+// Details
+func (e *apiError) Details() []gin.H {
+	return e.Detail
+}
+
+// Unwrap
+func (e *apiError) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is a sentinel with the same status, or an error
+// with the same status and name.
+func (e *apiError) Is(target error) bool {
+	t, ok := target.(*apiError)
+	if !ok {
+		return false
+	}
+	if t.sentinel {
+		return t.Code == e.Code
+	}
+	return t.Code == e.Code && t.Reason == e.Reason
+}
+
 // New
 func New(code int, name, message string) error {
 	return &apiError{
-		Status: code,
-		Name:   name,
+		Code:   code,
+		Reason: name,
 		Msg:    message,
 	}
 }
 
+// BadRequest
+func BadRequest(name, message string) error {
+	return New(http.StatusBadRequest, name, message)
+}
+
 // Unauthorized
 func Unauthorized(name, message string) error {
-	return &apiError{
-		Status: 401,
-		Name:   name,
-		Msg:    message,
-	}
+	return New(http.StatusUnauthorized, name, message)
 }
 
 // Forbidden
 func Forbidden(name, message string) error {
-	return &apiError{
-		Status: 403,
-		Name:   name,
-		Msg:    message,
-	}
+	return New(http.StatusForbidden, name, message)
 }
 
-// BadRequest
-func BadRequest(name, message string) error {
-	return &apiError{
-		Status: 400,
-		Name:   name,
-		Msg:    message,
-	}
+// NotFound
+func NotFound(name, message string) error {
+	return New(http.StatusNotFound, name, message)
+}
+
+// Conflict
+func Conflict(name, message string) error {
+	return New(http.StatusConflict, name, message)
+}
+
+// Unprocessable
+func Unprocessable(name, message string) error {
+	return New(http.StatusUnprocessableEntity, name, message)
+}
+
+// TooManyRequests
+func TooManyRequests(name, message string) error {
+	return New(http.StatusTooManyRequests, name, message)
 }
 
 // InternalServerError
 func InternalServerError(name, message string) error {
+	return New(http.StatusInternalServerError, name, message)
+}
+
+// Unavailable
+func Unavailable(name, message string) error {
+	return New(http.StatusServiceUnavailable, name, message)
+}
+
+// Timeout
+func Timeout(name, message string) error {
+	return New(http.StatusGatewayTimeout, name, message)
+}
+
+// Is reports whether any error in err's tree matches target.
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As finds the first error in err's tree that matches target.
+func As(err error, target any) bool {
+	return stderrors.As(err, target)
+}
+
+// Unwrap returns the result of calling the Unwrap method on err.
+func Unwrap(err error) error {
+	return stderrors.Unwrap(err)
+}
+
+// From returns the APIError in err's tree, if any.
+func From(err error) (APIError, bool) {
+	var e APIError
+	if stderrors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// WithDetails returns a copy of the APIError in err's tree with details
+// appended, e.g. errors.WithDetails(errors.ErrBadRequest, gin.H{...}).
+func WithDetails(err error, details ...gin.H) error {
+	c := *apiErrorOf(err)
+	c.sentinel = false
+	c.Detail = append(append(make([]gin.H, 0, len(c.Detail)+len(details)), c.Detail...), details...)
+	return &c
+}
+
+// WithCause returns a copy of the APIError in err's tree wrapping cause.
+func WithCause(err, cause error) error {
+	c := *apiErrorOf(err)
+	c.sentinel = false
+	c.cause = cause
+	return &c
+}
+
+// apiErrorOf returns the apiError in err's tree, or err wrapped in an
+// InternalServerError.
+func apiErrorOf(err error) *apiError {
+	var e *apiError
+	if As(err, &e) {
+		return e
+	}
 	return &apiError{
-		Status: 500,
-		Name:   name,
-		Msg:    message,
+		Code:   http.StatusInternalServerError,
+		Reason: "InternalServerError",
+		Msg:    err.Error(),
+		cause:  err,
 	}
 }
 
@@ -92,6 +242,40 @@ func (e *MultiError) Append(err ...Error) {
 	e.Errors = append(e.Errors, err...)
 }
 
+// AppendError adds errs to e, skipping nil errors. Errors without an
+// APIError in their tree are wrapped in an InternalServerError.
+func (e *MultiError) AppendError(errs ...error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		e.Errors = append(e.Errors, apiErrorOf(err))
+	}
+}
+
+// Status returns the highest status of the collected errors. Errors that are
+// not an APIError count as 500.
+func (e *MultiError) Status() (status int) {
+	for _, err := range e.Errors {
+		if s := apiErrorOf(err).Status(); s > status {
+			status = s
+		}
+	}
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	return
+}
+
+// Unwrap
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // Error
 func (e *MultiError) Error() string {
 	b, _ := json.Marshal(e)
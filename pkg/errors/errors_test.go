@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSentinelMatching(t *testing.T) {
+	err := fmt.Errorf("get invoice: %w", WithCause(NotFound("InvoiceNotFound", "invoice not found"), io.EOF))
+
+	if !Is(err, ErrNotFound) {
+		t.Error("expected error to match ErrNotFound")
+	}
+	if Is(err, ErrConflict) {
+		t.Error("expected error not to match ErrConflict")
+	}
+	if !Is(err, io.EOF) {
+		t.Error("expected error to unwrap to its cause")
+	}
+
+	e, ok := From(err)
+	if !ok {
+		t.Fatal("expected error to be an Error")
+	}
+	if e.Status() != http.StatusNotFound || e.Name() != "InvoiceNotFound" {
+		t.Errorf("unexpected error %s", e)
+	}
+}
+
+func TestWithDetailsDoesNotMutateSentinel(t *testing.T) {
+	err := WithDetails(ErrBadRequest, map[string]any{"field": "email"})
+
+	if e, _ := From(err); len(e.Details()) != 1 {
+		t.Errorf("expected details on the copy, got %v", e.Details())
+	}
+	if e, _ := From(ErrBadRequest); len(e.Details()) != 0 {
+		t.Error("expected sentinel details to be untouched")
+	}
+}
+
+func TestMultiErrorStatus(t *testing.T) {
+	multi := NewMultiError()
+	multi.Append(BadRequest("InvalidEmail", "email is invalid"), io.EOF)
+
+	if multi.Status() != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", multi.Status())
+	}
+	if !Is(multi, io.EOF) {
+		t.Error("expected multi error to unwrap wrapped errors")
+	}
+}
+
+func TestMultiErrorAppendError(t *testing.T) {
+	multi := NewMultiError()
+	multi.AppendError(nil, NotFound("CustomerNotFound", "customer not found"), io.EOF)
+
+	if len(multi.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(multi.Errors))
+	}
+	e, ok := multi.Errors[1].(APIError)
+	if !ok || e.Status() != http.StatusInternalServerError {
+		t.Errorf("expected io.EOF wrapped in a 500, got %#v", multi.Errors[1])
+	}
+	if !Is(multi, io.EOF) {
+		t.Error("expected multi error to unwrap wrapped errors")
+	}
+}
@@ -0,0 +1,321 @@
+package errors
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// GRPCStatus lets status.FromError and status.Convert understand apiError.
+func (e *apiError) GRPCStatus() *status.Status {
+	st := status.New(CodeFromHTTP(e.Code), e.Msg)
+
+	info := &errdetails.ErrorInfo{
+		Reason: e.Reason,
+		Metadata: map[string]string{
+			"status": strconv.Itoa(e.Code),
+		},
+	}
+
+	if violations := fieldViolations(e.Detail); len(violations) > 0 {
+		if ds, err := st.WithDetails(info, &errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			return ds
+		}
+		return st
+	}
+
+	if ds, err := st.WithDetails(info); err == nil {
+		return ds
+	}
+	return st
+}
+
+// GRPCStatus lets status.FromError and status.Convert understand MultiError.
+// Every error is kept as its own ErrorInfo so FromStatus can rebuild it, and
+// counts the field violations it added to the shared BadRequest.
+func (e *MultiError) GRPCStatus() *status.Status {
+	var (
+		messages   = make([]string, 0, len(e.Errors))
+		infos      = make([]*errdetails.ErrorInfo, 0, len(e.Errors))
+		violations = make([]*errdetails.BadRequest_FieldViolation, 0)
+	)
+
+	for _, item := range e.Errors {
+		err := apiErrorOf(item)
+		fv := fieldViolations(err.Details())
+		messages = append(messages, err.Message())
+		infos = append(infos, &errdetails.ErrorInfo{
+			Reason: err.Name(),
+			Metadata: map[string]string{
+				"status":     strconv.Itoa(err.Status()),
+				"message":    err.Message(),
+				"violations": strconv.Itoa(len(fv)),
+			},
+		})
+		violations = append(violations, fv...)
+	}
+
+	st := status.New(CodeFromHTTP(e.Status()), strings.Join(messages, "; "))
+
+	details := make([]protoadapt.MessageV1, 0, len(infos)+1)
+	for _, info := range infos {
+		details = append(details, info)
+	}
+	if len(violations) > 0 {
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	if ds, err := st.WithDetails(details...); err == nil {
+		return ds
+	}
+	return st
+}
+
+// ToStatus converts any error into a gRPC status. apiError and MultiError
+// keep their code and details even when wrapped, context errors map to
+// Canceled and DeadlineExceeded and anything else becomes Internal.
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	var multi *MultiError
+	if As(err, &multi) {
+		return multi.GRPCStatus()
+	}
+
+	var e *apiError
+	if As(err, &e) {
+		return e.GRPCStatus()
+	}
+
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	if st := status.FromContextError(err); st.Code() != codes.Unknown {
+		return st
+	}
+
+	return status.New(codes.Internal, err.Error())
+}
+
+// FromStatus rebuilds the apiError, or MultiError when the status carries
+// more than one ErrorInfo, described by st.
+func FromStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	var (
+		infos   []*errdetails.ErrorInfo
+		details []gin.H
+	)
+
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			infos = append(infos, v)
+		case *errdetails.BadRequest:
+			for _, fv := range v.GetFieldViolations() {
+				details = append(details, gin.H{
+					"field":   fv.GetField(),
+					"message": fv.GetDescription(),
+				})
+			}
+		}
+	}
+
+	if len(infos) > 1 {
+		multi := NewMultiError()
+		for i, info := range infos {
+			n := violationCount(info)
+			if i == 0 {
+				// the first error also keeps violations no ErrorInfo counts
+				n = len(details)
+				for _, other := range infos[1:] {
+					n -= violationCount(other)
+				}
+			}
+			n = min(max(n, 0), len(details))
+
+			e := &apiError{
+				Code:   statusFromInfo(info, st.Code()),
+				Reason: info.GetReason(),
+				Msg:    info.GetMetadata()["message"],
+			}
+			if n > 0 {
+				e.Detail, details = details[:n:n], details[n:]
+			}
+			multi.Append(e)
+		}
+		return multi
+	}
+
+	e := &apiError{
+		Code:   HTTPFromCode(st.Code()),
+		Reason: st.Code().String(),
+		Msg:    st.Message(),
+		Detail: details,
+	}
+
+	if len(infos) == 1 {
+		e.Code = statusFromInfo(infos[0], st.Code())
+		e.Reason = infos[0].GetReason()
+	}
+
+	return e
+}
+
+// FromError rebuilds the apiError carried by a gRPC error returned from a
+// client call. Errors that are not gRPC statuses are returned as is.
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return FromStatus(st)
+}
+
+// UnaryServerInterceptor converts handler errors into gRPC statuses.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, ToStatus(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor converts handler errors into gRPC statuses.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return ToStatus(err).Err()
+		}
+		return nil
+	}
+}
+
+// UnaryClientInterceptor converts gRPC errors back into apiError.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// CodeFromHTTP maps an HTTP status to the closest gRPC code.
+func CodeFromHTTP(code int) codes.Code {
+	switch code {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	switch {
+	case code >= 500:
+		return codes.Internal
+	case code >= 400:
+		return codes.InvalidArgument
+	}
+	return codes.Unknown
+}
+
+// HTTPFromCode maps a gRPC code to the closest HTTP status.
+func HTTPFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func statusFromInfo(info *errdetails.ErrorInfo, code codes.Code) int {
+	if s, err := strconv.Atoi(info.GetMetadata()["status"]); err == nil {
+		return s
+	}
+	return HTTPFromCode(code)
+}
+
+// violationCount returns the number of field violations info counts.
+func violationCount(info *errdetails.ErrorInfo) int {
+	n, _ := strconv.Atoi(info.GetMetadata()["violations"])
+	return max(n, 0)
+}
+
+func fieldViolations(details []gin.H) []*errdetails.BadRequest_FieldViolation {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(details))
+	for _, d := range details {
+		field, ok := d["field"].(string)
+		if !ok {
+			continue
+		}
+
+		description, _ := d["message"].(string)
+		if description == "" {
+			description, _ = d["tag"].(string)
+		}
+
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+	return violations
+}
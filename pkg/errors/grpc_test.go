@@ -0,0 +1,131 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	err := apiErrorOf(WithDetails(
+		BadRequest("InvalidRequest", "email is required"),
+		gin.H{"field": "email", "message": "email is a required field"},
+	))
+
+	st := ToStatus(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %s", st.Code())
+	}
+
+	got, ok := FromStatus(st).(*apiError)
+	if !ok {
+		t.Fatalf("expected *apiError, got %T", FromStatus(st))
+	}
+	if got.Status() != err.Status() || got.Name() != err.Name() || got.Message() != err.Message() {
+		t.Errorf("unexpected error %+v", got)
+	}
+	if len(got.Details()) != 1 || got.Details()[0]["field"] != "email" {
+		t.Errorf("unexpected details %+v", got.Details())
+	}
+}
+
+func TestMultiErrorRoundTrip(t *testing.T) {
+	multi := NewMultiError()
+	multi.Append(
+		BadRequest("InvalidEmail", "email is invalid"),
+		Forbidden("Forbidden", "not allowed"),
+	)
+
+	st := ToStatus(multi)
+	if st.Code() != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %s", st.Code())
+	}
+
+	got, ok := FromStatus(st).(*MultiError)
+	if !ok {
+		t.Fatalf("expected *MultiError, got %T", FromStatus(st))
+	}
+	if len(got.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(got.Errors))
+	}
+}
+
+func TestToStatusRawError(t *testing.T) {
+	if code := ToStatus(http.ErrHandlerTimeout).Code(); code != codes.Internal {
+		t.Errorf("expected Internal, got %s", code)
+	}
+}
+
+func TestToStatusWrappedError(t *testing.T) {
+	err := fmt.Errorf("load customer: %w", NotFound("CustomerNotFound", "customer not found"))
+
+	st := ToStatus(err)
+	if st.Code() != codes.NotFound {
+		t.Fatalf("expected NotFound, got %s", st.Code())
+	}
+	if st.Message() != "customer not found" {
+		t.Errorf("unexpected message %q", st.Message())
+	}
+	if got := FromStatus(st).(*apiError); got.Name() != "CustomerNotFound" {
+		t.Errorf("unexpected name %q", got.Name())
+	}
+}
+
+func TestMultiErrorRoundTripKeepsFieldViolations(t *testing.T) {
+	multi := NewMultiError()
+	multi.Append(
+		Forbidden("Forbidden", "not allowed"),
+		WithDetails(BadRequest("InvalidRequest", "request is invalid"),
+			gin.H{"field": "email", "message": "email is invalid"},
+			gin.H{"field": "name", "message": "name is required"},
+		),
+		WithDetails(Unprocessable("InvalidItem", "item is invalid"),
+			gin.H{"field": "items[0].sku", "message": "sku is required"},
+		),
+	)
+
+	got, ok := FromStatus(ToStatus(multi)).(*MultiError)
+	if !ok || len(got.Errors) != 3 {
+		t.Fatalf("unexpected error %+v", got)
+	}
+
+	var fields [][]string
+	for _, err := range got.Errors {
+		var f []string
+		for _, d := range err.(*apiError).Details() {
+			f = append(f, d["field"].(string))
+		}
+		fields = append(fields, f)
+	}
+	if fmt.Sprint(fields) != "[[] [email name] [items[0].sku]]" {
+		t.Errorf("unexpected field violations %v", fields)
+	}
+}
+
+func TestCodeFromHTTP(t *testing.T) {
+	tests := []struct {
+		status int
+		want   codes.Code
+	}{
+		{http.StatusOK, codes.OK},
+		{http.StatusBadRequest, codes.InvalidArgument},
+		{http.StatusNotFound, codes.NotFound},
+		{http.StatusMethodNotAllowed, codes.InvalidArgument},
+		{http.StatusRequestTimeout, codes.InvalidArgument},
+		{http.StatusGone, codes.InvalidArgument},
+		{http.StatusRequestEntityTooLarge, codes.InvalidArgument},
+		{499, codes.Canceled},
+		{http.StatusBadGateway, codes.Internal},
+		{http.StatusServiceUnavailable, codes.Unavailable},
+		{http.StatusFound, codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		if got := CodeFromHTTP(tt.status); got != tt.want {
+			t.Errorf("CodeFromHTTP(%d) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
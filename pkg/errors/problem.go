@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Extension members
+	Name          string  `json:"name,omitempty"`
+	InvalidParams []gin.H `json:"invalid_params,omitempty"`
+	Errors        []gin.H `json:"errors,omitempty"`
+	RequestID     string  `json:"request_id,omitempty"`
+	TraceID       string  `json:"trace_id,omitempty"`
+}
+
+// NewProblem builds the problem details for err. typeBase is prefixed to the
+// error name to build the problem type, "about:blank" is used when empty.
+func NewProblem(err error, typeBase string) *Problem {
+	var multi *MultiError
+	if As(err, &multi) {
+		p := newProblem(multi.Status(), "MultiError", typeBase)
+		messages := make([]string, 0, len(multi.Errors))
+		for _, item := range multi.Errors {
+			e := apiErrorOf(item)
+			messages = append(messages, e.Message())
+			p.Errors = append(p.Errors, gin.H{
+				"status":  e.Status(),
+				"name":    e.Name(),
+				"message": e.Message(),
+			})
+			p.InvalidParams = append(p.InvalidParams, e.Details()...)
+		}
+		p.Detail = strings.Join(messages, "; ")
+		return p
+	}
+
+	e := apiErrorOf(err)
+
+	p := newProblem(e.Status(), e.Name(), typeBase)
+	p.Detail = e.Message()
+	p.InvalidParams = e.Details()
+	return p
+}
+
+func newProblem(status int, name, typeBase string) *Problem {
+	p := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Name:   name,
+	}
+	if typeBase != "" {
+		p.Type = strings.TrimSuffix(typeBase, "/") + "/" + name
+	}
+	return p
+}
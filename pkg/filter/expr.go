@@ -0,0 +1,271 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm/clause"
+)
+
+const maxDepth = 32
+
+// ParseExpr builds a filter from an AIP-160 style expression such as
+//
+//	status = "active" AND (amount >= 100 OR NOT customer:"acme")
+//
+// Supported are AND (or juxtaposition), OR, NOT (or a leading "-"),
+// parentheses and the comparisons =, !=, <, <=, >, >= and ":" which matches
+// substrings of string fields. Comparing with the bare word null tests for
+// NULL.
+func (s Schema) ParseExpr(expr string) (*Filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return &Filter{}, nil
+	}
+
+	p := &parser{schema: s, tokens: tokens}
+	e, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, syntaxError("unexpected %q", p.peek().text)
+	}
+	return &Filter{exprs: []clause.Expression{e}}, nil
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")"})
+			i++
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(r) && r[j] != c; j++ {
+				if r[j] == '\\' && j+1 < len(r) {
+					j++
+				}
+				b.WriteRune(r[j])
+			}
+			if j >= len(r) {
+				return nil, syntaxError("unterminated string")
+			}
+			tokens = append(tokens, token{tokString, b.String()})
+			i = j + 1
+		case strings.ContainsRune("=!<>:", c):
+			op := string(c)
+			if i+1 < len(r) && r[i+1] == '=' && c != '=' && c != ':' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, syntaxError("unexpected !")
+			}
+			tokens = append(tokens, token{tokOp, op})
+			i += len(op)
+		case c == '-' && i+1 < len(r) && (unicode.IsLetter(r[i+1]) || r[i+1] == '(' || r[i+1] == '_'):
+			tokens = append(tokens, token{tokNot, "-"})
+			i++
+		default:
+			j := i
+			for j < len(r) && !unicode.IsSpace(r[j]) && !strings.ContainsRune(`()=!<>:"'`, r[j]) {
+				j++
+			}
+			word := string(r[i:j])
+			if word == "NOT" {
+				tokens = append(tokens, token{tokNot, word})
+			} else {
+				tokens = append(tokens, token{tokWord, word})
+			}
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	schema Schema
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) isWord(text string) bool {
+	return !p.done() && p.peek().kind == tokWord && p.peek().text == text
+}
+
+func (p *parser) or(depth int) (clause.Expression, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := []clause.Expression{left}
+	for p.isWord("OR") {
+		p.next()
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return clause.Or(exprs...), nil
+}
+
+func (p *parser) and(depth int) (clause.Expression, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := []clause.Expression{left}
+	for !p.done() && p.peek().kind != tokRParen && !p.isWord("OR") {
+		if p.isWord("AND") {
+			p.next()
+		}
+		right, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return clause.And(exprs...), nil
+}
+
+func (p *parser) unary(depth int) (clause.Expression, error) {
+	if depth > maxDepth {
+		return nil, syntaxError("expression is nested too deeply")
+	}
+	if p.done() {
+		return nil, syntaxError("unexpected end of expression")
+	}
+
+	if p.peek().kind == tokNot {
+		p.next()
+		e, err := p.unary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		// clause.Not negates each condition of a group on its own, which
+		// turns NOT (a AND b) into NOT a AND NOT b
+		return clause.Expr{SQL: "NOT (?)", Vars: []any{e}}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		e, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.next().kind != tokRParen {
+			return nil, syntaxError("missing )")
+		}
+		return e, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (clause.Expression, error) {
+	name := p.next()
+	if name.kind != tokWord {
+		return nil, syntaxError("expected a field, got %q", name.text)
+	}
+	if p.done() || p.peek().kind != tokOp {
+		return nil, syntaxError("expected a comparison after %q", name.text)
+	}
+	op := p.next().text
+
+	if p.done() {
+		return nil, syntaxError("expected a value after %q", op)
+	}
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, syntaxError("expected a value after %q, got %q", op, value.text)
+	}
+
+	if value.kind == tokWord && value.text == "null" {
+		switch op {
+		case "=":
+			return p.schema.condition(name.text, Null, "true")
+		case "!=":
+			return p.schema.condition(name.text, Null, "false")
+		}
+	}
+
+	switch op {
+	case "=":
+		return p.schema.condition(name.text, Eq, value.text)
+	case "!=":
+		return p.schema.condition(name.text, Ne, value.text)
+	case "<":
+		return p.schema.condition(name.text, Lt, value.text)
+	case "<=":
+		return p.schema.condition(name.text, Lte, value.text)
+	case ">":
+		return p.schema.condition(name.text, Gt, value.text)
+	case ">=":
+		return p.schema.condition(name.text, Gte, value.text)
+	case ":":
+		if field, ok := p.schema[name.text]; ok && field.Type == String {
+			return p.schema.condition(name.text, Like, value.text)
+		}
+		return p.schema.condition(name.text, Eq, value.text)
+	}
+
+	return nil, syntaxError("unknown comparison %q", op)
+}
+
+func syntaxError(format string, args ...any) error {
+	return invalid("filter", fmt.Sprintf(format, args...))
+}
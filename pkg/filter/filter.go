@@ -0,0 +1,213 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidFilter is returned for filters referencing unknown fields,
+// disallowed operators or values of the wrong type.
+var ErrInvalidFilter = errors.BadRequest("InvalidFilter", "filter is invalid")
+
+// Type is the type of a filterable field, used to parse its values.
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Float
+	Bool
+	Time
+)
+
+// Operator is a comparison a field can be filtered with.
+type Operator string
+
+const (
+	Eq      Operator = "eq"
+	Ne      Operator = "ne"
+	Gt      Operator = "gt"
+	Gte     Operator = "gte"
+	Lt      Operator = "lt"
+	Lte     Operator = "lte"
+	In      Operator = "in"
+	Nin     Operator = "nin"
+	Like    Operator = "like"
+	Between Operator = "between"
+	Null    Operator = "null"
+)
+
+var defaultOperators = map[Type][]Operator{
+	String: {Eq, Ne, In, Nin, Like, Null},
+	Int:    {Eq, Ne, Gt, Gte, Lt, Lte, In, Nin, Between, Null},
+	Float:  {Eq, Ne, Gt, Gte, Lt, Lte, In, Nin, Between, Null},
+	Bool:   {Eq, Ne, Null},
+	Time:   {Eq, Ne, Gt, Gte, Lt, Lte, Between, Null},
+}
+
+// Field describes a filterable field. Operators defaults to the operators
+// that make sense for Type.
+type Field struct {
+	Column    string
+	Type      Type
+	Operators []Operator
+}
+
+func (f Field) allows(op Operator) bool {
+	ops := f.Operators
+	if ops == nil {
+		ops = defaultOperators[f.Type]
+	}
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema whitelists the filterable fields of a resource, keyed by the name
+// used in requests.
+type Schema map[string]Field
+
+// Filter is a parsed filter, usable as a gorm scope.
+type Filter struct {
+	exprs []clause.Expression
+}
+
+// Empty reports whether the filter has no conditions.
+func (f *Filter) Empty() bool {
+	return f == nil || len(f.exprs) == 0
+}
+
+// Scope returns a gorm scope applying the filter, e.g.
+//
+//	db.Scopes(f.Scope(), p.Paginate()).Find(&invoices)
+func (f *Filter) Scope() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.Empty() {
+			return db
+		}
+		return db.Where(clause.And(f.exprs...))
+	}
+}
+
+// condition compiles a single comparison after validating it against s.
+func (s Schema) condition(name string, op Operator, values ...string) (clause.Expression, error) {
+	field, ok := s[name]
+	if !ok {
+		return nil, invalid(name, fmt.Sprintf("%s can't be filtered on", name))
+	}
+	if !field.allows(op) {
+		return nil, invalid(name, fmt.Sprintf("%s doesn't support the %s operator", name, op))
+	}
+
+	column := clause.Column{Name: field.Column}
+
+	if op == Null {
+		isNull, err := strconv.ParseBool(first(values))
+		if err != nil {
+			return nil, invalid(name, fmt.Sprintf("%s[null] must be true or false", name))
+		}
+		if isNull {
+			return clause.Expr{SQL: "? IS NULL", Vars: []any{column}}, nil
+		}
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []any{column}}, nil
+	}
+
+	parsed := make([]any, 0, len(values))
+	for _, v := range values {
+		pv, err := field.parse(v)
+		if err != nil {
+			return nil, invalid(name, fmt.Sprintf("%s: %s", name, err))
+		}
+		parsed = append(parsed, pv)
+	}
+
+	switch op {
+	case In, Nin:
+		if len(parsed) == 0 {
+			return nil, invalid(name, fmt.Sprintf("%s[%s] requires at least one value", name, op))
+		}
+		if op == Nin {
+			return clause.Not(clause.IN{Column: column, Values: parsed}), nil
+		}
+		return clause.IN{Column: column, Values: parsed}, nil
+	case Between:
+		if len(parsed) != 2 {
+			return nil, invalid(name, fmt.Sprintf("%s[between] requires two values", name))
+		}
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, parsed[0], parsed[1]}}, nil
+	}
+
+	if len(parsed) != 1 {
+		return nil, invalid(name, fmt.Sprintf("%s[%s] requires a single value", name, op))
+	}
+	value := parsed[0]
+
+	switch op {
+	case Eq:
+		return clause.Eq{Column: column, Value: value}, nil
+	case Ne:
+		return clause.Neq{Column: column, Value: value}, nil
+	case Gt:
+		return clause.Gt{Column: column, Value: value}, nil
+	case Gte:
+		return clause.Gte{Column: column, Value: value}, nil
+	case Lt:
+		return clause.Lt{Column: column, Value: value}, nil
+	case Lte:
+		return clause.Lte{Column: column, Value: value}, nil
+	case Like:
+		return clause.Expr{
+			SQL:  "? LIKE ? ESCAPE '!'",
+			Vars: []any{column, "%" + escapeLike(fmt.Sprint(value)) + "%"},
+		}, nil
+	}
+
+	return nil, invalid(name, fmt.Sprintf("unknown operator %s", op))
+}
+
+func (f Field) parse(v string) (any, error) {
+	switch f.Type {
+	case Int:
+		return strconv.ParseInt(v, 10, 64)
+	case Float:
+		return strconv.ParseFloat(v, 64)
+	case Bool:
+		return strconv.ParseBool(v)
+	case Time:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.DateOnly, v)
+	}
+	return v, nil
+}
+
+// escapeLike escapes LIKE wildcards with "!", which unlike "\" needs no
+// quoting in any of the supported dialects.
+func escapeLike(v string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(v)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func invalid(field, message string) error {
+	return errors.WithDetails(ErrInvalidFilter, gin.H{
+		"field":   field,
+		"message": message,
+	})
+}
@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/smallbiznis/go-lib/internal/testdb"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"github.com/smallbiznis/go-lib/pkg/pagination"
+	"gorm.io/gorm"
+)
+
+type invoice struct {
+	ID        int64
+	Status    string
+	Customer  string
+	Amount    int64
+	CreatedAt time.Time
+}
+
+var schema = Schema{
+	"status":     {Column: "status", Type: String},
+	"customer":   {Column: "customer", Type: String},
+	"amount":     {Column: "amount", Type: Int},
+	"created_at": {Column: "created_at", Type: Time},
+}
+
+func seedInvoices(t *testing.T) *gorm.DB {
+	db := testdb.New(t, &invoice{})
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []invoice{
+		{ID: 1, Status: "paid", Customer: "acme", Amount: 50, CreatedAt: day},
+		{ID: 2, Status: "paid", Customer: "acme_corp", Amount: 150, CreatedAt: day.AddDate(0, 0, 1)},
+		{ID: 3, Status: "open", Customer: "globex", Amount: 250, CreatedAt: day.AddDate(0, 0, 2)},
+		{ID: 4, Status: "void", Customer: "initech", Amount: 350, CreatedAt: day.AddDate(0, 1, 0)},
+	}
+	if err := db.Create(&invoices).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func find(t *testing.T, db *gorm.DB, f *Filter) (ids []int64) {
+	var items []invoice
+	p := pagination.Pagination{Size: 10, Page: 1}
+	if err := db.Scopes(f.Scope(), p.Paginate()).Order("id").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParse(t *testing.T) {
+	db := seedInvoices(t)
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"status=paid&pageSize=10", []int64{1, 2}},
+		{"amount[gte]=100&amount[lt]=300", []int64{2, 3}},
+		{"status[in]=open,void", []int64{3, 4}},
+		{"created_at[between]=2024-01-02,2024-01-31", []int64{2, 3}},
+		{"customer[like]=acme_", []int64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			f, err := schema.Parse(values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := find(t, db, f); !equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	db := seedInvoices(t)
+
+	tests := []struct {
+		expr string
+		want []int64
+	}{
+		{`status = "paid"`, []int64{1, 2}},
+		{`status = paid amount > 100`, []int64{2}},
+		{`status = "open" OR amount <= 50`, []int64{1, 3}},
+		{`NOT status = "paid" AND -(amount >= 300)`, []int64{3}},
+		{`NOT (status = "paid" AND amount > 100)`, []int64{1, 3, 4}},
+		{`-(status = paid amount > 100)`, []int64{1, 3, 4}},
+		{`NOT (status = "open" OR amount <= 50)`, []int64{2, 4}},
+		{`customer:"acme"`, []int64{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := schema.ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := find(t, db, f); !equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInvalidFilters(t *testing.T) {
+	for _, query := range []string{"password[eq]=x", "status[gt]=a", "amount=abc"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := schema.Parse(values); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s: expected ErrInvalidFilter, got %v", query, err)
+		}
+	}
+
+	for _, expr := range []string{`password = "x"`, `status = `, `(status = "paid"`, `amount > "abc"`} {
+		if _, err := schema.ParseExpr(expr); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s: expected ErrInvalidFilter, got %v", expr, err)
+		}
+	}
+}
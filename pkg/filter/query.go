@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var queryKey = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.]*)(?:\[([a-z]+)\])?$`)
+
+// Parse builds a filter from query string values such as
+//
+//	status=active&amount[gte]=100&created_at[between]=2024-01-01,2024-02-01
+//
+// Plain keys missing from s are ignored so pagination and other parameters
+// can share the query string. Keys with an operator must be in s.
+func (s Schema) Parse(values url.Values) (*Filter, error) {
+	// map iteration is random, keep the generated SQL stable
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f := &Filter{}
+	for _, key := range keys {
+		m := queryKey.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+
+		name, op := m[1], Operator(m[2])
+		if op == "" {
+			if _, ok := s[name]; !ok {
+				continue
+			}
+			op = Eq
+		}
+
+		for _, v := range values[key] {
+			args := []string{v}
+			if op == In || op == Nin || op == Between {
+				args = splitList(v)
+			}
+
+			expr, err := s.condition(name, op, args...)
+			if err != nil {
+				return nil, err
+			}
+			f.exprs = append(f.exprs, expr)
+		}
+	}
+
+	return f, nil
+}
+
+func splitList(v string) []string {
+	parts := strings.Split(v, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
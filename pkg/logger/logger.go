@@ -1,7 +1,7 @@
 package logger
 
 import (
-	"github.com/smallbiznis/go-lib/pkg/env"
+	"github.com/smallbiznis/go-lib/pkg/config"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -9,24 +9,71 @@ import (
 var (
 	NewZapLogger = fx.Module("zap.Logger", fx.Options(
 		fx.Provide(
-			InitLogger,
+			NewConfig,
+			New,
+			Level,
 		),
 	))
 )
 
+// Config sets the service fields added to every log entry.
+type Config struct {
+	ServiceName      string `env:"SERVICE_NAME" default:"example"`
+	ServiceVersion   string `env:"SERVICE_VERSION" default:"v1.0.0"`
+	ServiceNamespace string `env:"SERVICE_NAMESPACE" default:"smallbiznis"`
+	// Env selects the production encoder and sampling when "production".
+	Env string `env:"ENV" default:"development"`
+}
+
+// NewConfig reads the logger configuration from the environment.
+func NewConfig() (*Config, error) {
+	return config.Load[Config]()
+}
+
+// level is shared by every logger built by InitLogger so it can be changed
+// at runtime, e.g. from a config.Watcher subscription.
+var level = zap.NewAtomicLevel()
+
+// Level returns the level of the loggers built by InitLogger.
+//
+//	w.Subscribe(func(_, cfg Config) {
+//		level.UnmarshalText([]byte(cfg.LogLevel))
+//	})
+func Level() zap.AtomicLevel {
+	return level
+}
+
+// InitLogger builds the logger from the environment, panicking when it
+// can't be built.
 func InitLogger() (log *zap.Logger) {
+	cfg, err := NewConfig()
+	if err != nil {
+		panic(err)
+	}
+	return zap.Must(New(cfg))
+}
+
+// New builds the logger described by cfg and makes it the global logger.
+func New(cfg *Config) (*zap.Logger, error) {
 	fields := zap.Fields(
-		zap.String("service_name", env.Lookup("SERVICE_NAME", "example")),
-		zap.String("service_version", env.Lookup("SERVICE_VERSION", "v1.0.0")),
-		zap.String("service_namespace", env.Lookup("SERVICE_NAMESPACE", "smallbiznis")),
+		zap.String("service_name", cfg.ServiceName),
+		zap.String("service_version", cfg.ServiceVersion),
+		zap.String("service_namespace", cfg.ServiceNamespace),
 	)
 
-	log = zap.Must(zap.NewDevelopment(fields))
-	if env.Lookup("ENV", "development") == "production" {
-		log = zap.Must(zap.NewProduction(fields))
+	zcfg := zap.NewDevelopmentConfig()
+	if cfg.Env == "production" {
+		zcfg = zap.NewProductionConfig()
+	}
+	level.SetLevel(zcfg.Level.Level())
+	zcfg.Level = level
+
+	log, err := zcfg.Build(fields)
+	if err != nil {
+		return nil, err
 	}
 
 	zap.ReplaceGlobals(log)
 
-	return log
+	return log, nil
 }
@@ -2,89 +2,180 @@ package middleware
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/smallbiznis/go-lib/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func HandleError(translate ut.Translator) gin.HandlerFunc {
+// ErrorFormat selects how HandleError renders errors.
+type ErrorFormat int
+
+const (
+	// ErrorFormatEnvelope renders {"error": {...}}.
+	ErrorFormatEnvelope ErrorFormat = iota
+	// ErrorFormatProblem renders RFC 7807 application/problem+json.
+	ErrorFormatProblem
+)
+
+type errorOptions struct {
+	format      ErrorFormat
+	problemType string
+}
+
+type ErrorOption func(*errorOptions)
+
+// WithErrorFormat
+func WithErrorFormat(format ErrorFormat) ErrorOption {
+	return func(o *errorOptions) {
+		o.format = format
+	}
+}
+
+// WithProblemType sets the URI prefixed to the error name to build the
+// problem "type" member.
+func WithProblemType(uri string) ErrorOption {
+	return func(o *errorOptions) {
+		o.problemType = uri
+	}
+}
+
+func HandleError(translate ut.Translator, opts ...ErrorOption) gin.HandlerFunc {
+	o := &errorOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return func(c *gin.Context) {
 		c.Next()
 		if err := c.Errors.Last(); err != nil {
-			c.JSON(
-				validationError(err.Err, translate),
-			)
+			code, e := validationError(err.Err, translate)
+			if o.format == ErrorFormatProblem {
+				problem(c, code, e, o.problemType)
+				return
+			}
+			c.JSON(code, gin.H{
+				"error": e,
+			})
 		}
 	}
 }
 
-func validationError(err error, translate ut.Translator) (code int, obj any) {
-	code = 500
-	obj = gin.H{
-		"error": errors.InternalServerError("InternalServerError", err.Error()),
+func problem(c *gin.Context, code int, err error, typeBase string) {
+	p := errors.NewProblem(err, typeBase)
+	p.Status = code
+
+	requestID := c.Writer.Header().Get("X-Request-Id")
+	if requestID == "" {
+		requestID = c.GetHeader("X-Request-Id")
+	}
+	if requestID != "" {
+		p.RequestID = requestID
+		p.Instance = "urn:request-id:" + requestID
 	}
 
+	if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+		p.TraceID = span.TraceID().String()
+		if p.Instance == "" {
+			p.Instance = "urn:trace-id:" + p.TraceID
+		}
+	}
+
+	b, _ := json.Marshal(p)
+	c.Data(code, errors.ProblemContentType, b)
+}
+
+func validationError(err error, translate ut.Translator) (code int, obj error) {
+	code = 500
+	obj = errors.InternalServerError("InternalServerError", err.Error())
+
 	// Handle error io.EOF request body empty
 	if err == io.EOF {
 		code = 400
-		obj = gin.H{
-			"error": errors.BadRequest("InvalidRequest", "request can't be empty"),
-		}
+		obj = errors.BadRequest("InvalidRequest", "request can't be empty")
 		return
 	}
 
 	// Handle error *json.SyntaxError
 	if _, ok := err.(*json.SyntaxError); ok {
 		code = 400
-		obj = gin.H{
-			"error": errors.BadRequest("InvalidRequest", err.Error()),
-		}
+		obj = errors.BadRequest("InvalidRequest", err.Error())
+		return
+	}
+
+	// Handle error validator.ValidationErrors
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) && len(verrs) > 0 {
+		code = 400
+		obj = fieldErrors(verrs, translate)
 		return
 	}
 
 	// Handle error *validator.fieldError
 	if e, ok := err.(validator.FieldError); ok {
 		code = 400
-		msg := fmt.Errorf(e.Translate(translate)).Error()
-		// newErr := errors.BadRequest("InvalidRequest", msg)
-		obj = gin.H{
-			"error": gin.H{
-				"status":  code,
-				"name":    "InvalidRequest",
-				"message": msg,
-				"details": []gin.H{
-					{
-						"field": e.Field(),
-						"tags":  e.Tag(),
-					},
-				},
-			},
-		}
+		obj = fieldErrors([]validator.FieldError{e}, translate)
 		return
 	}
 
 	// Handle error uuid.isInvalidLength
 	if uuid.IsInvalidLengthError(err) {
 		code = 400
-		obj = gin.H{
-			"error": err,
-		}
+		obj = errors.BadRequest("InvalidRequest", err.Error())
+		return
+	}
+
+	// Handle error multi business logic
+	var multi *errors.MultiError
+	if errors.As(err, &multi) {
+		code = multi.Status()
+		obj = multi
 		return
 	}
 
 	// Handle error business logic
-	if _, ok := err.(errors.Error); ok {
-		code = 400
-		obj = gin.H{
-			"error": err,
-		}
+	if e, ok := errors.From(err); ok {
+		code = e.Status()
+		obj = e
 		return
 	}
 
 	return
 }
+
+// fieldErrors reports every failed field, using the json path of the field
+// (e.g. items[2].sku) resolved by the validator tag name func.
+func fieldErrors(fes []validator.FieldError, translate ut.Translator) error {
+	messages := make([]string, 0, len(fes))
+	details := make([]gin.H, 0, len(fes))
+	for _, fe := range fes {
+		msg := fe.Error()
+		if translate != nil {
+			msg = fe.Translate(translate)
+		}
+
+		messages = append(messages, msg)
+		details = append(details, gin.H{
+			"field":   fieldPath(fe),
+			"tag":     fe.Tag(),
+			"param":   fe.Param(),
+			"message": msg,
+		})
+	}
+
+	return errors.WithDetails(errors.BadRequest("InvalidRequest", strings.Join(messages, "; ")), details...)
+}
+
+// fieldPath strips the top level struct name from the field namespace.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"github.com/smallbiznis/go-lib/pkg/validator"
+)
+
+var validate = validator.NewValidator()
+
+func newErrorRouter(handler gin.HandlerFunc, opts ...ErrorOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	validator.RegisterBinding(validate)
+
+	r := gin.New()
+	r.Use(HandleError(validator.NewTranslation(validate), opts...))
+	r.POST("/", handler)
+	return r
+}
+
+func TestHandleErrorStatus(t *testing.T) {
+	r := newErrorRouter(func(c *gin.Context) {
+		c.Error(errors.NotFound("InvoiceNotFound", "invoice not found"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleErrorValidationErrors(t *testing.T) {
+	type item struct {
+		Sku string `json:"sku" validate:"required"`
+	}
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+		Items []item `json:"items" validate:"dive"`
+	}
+
+	r := newErrorRouter(func(c *gin.Context) {
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+		}
+	})
+
+	body := `{"email":"invalid","items":[{"sku":"a"},{"sku":"b"},{}]}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+
+	var resp struct {
+		Error struct {
+			Details []map[string]string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	details := resp.Error.Details
+	if len(details) != 2 {
+		t.Fatalf("expected 2 details, got %+v", details)
+	}
+	if details[0]["field"] != "email" || details[0]["tag"] != "email" || details[0]["message"] != "email must be a valid email address" {
+		t.Errorf("unexpected detail %+v", details[0])
+	}
+	if details[1]["field"] != "items[2].sku" || details[1]["message"] != "sku is a required field" {
+		t.Errorf("unexpected detail %+v", details[1])
+	}
+}
+
+func TestHandleErrorProblem(t *testing.T) {
+	r := newErrorRouter(func(c *gin.Context) {
+		c.Error(errors.Conflict("InvoiceExists", "invoice already exists"))
+	}, WithErrorFormat(ErrorFormatProblem), WithProblemType("https://errors.smallbiznis.dev"))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != errors.ProblemContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	var p errors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != http.StatusConflict || p.Type != "https://errors.smallbiznis.dev/InvoiceExists" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+	if p.Instance != "urn:request-id:req-1" {
+		t.Errorf("unexpected instance %q", p.Instance)
+	}
+}
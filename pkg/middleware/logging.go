@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,16 +18,31 @@ import (
 
 var (
 	// List of endpoints to be excluded from logging
-	excludedEndpoints = []string{
+	defaultExcludedEndpoints = []string{
 		"/metrics",
 		"/health/liveness",
 		"/health/readiness",
 	}
+
+	// excludedEndpoints replaces defaultExcludedEndpoints once set.
+	excludedEndpoints atomic.Pointer[[]string]
 )
 
+// SetExcludedEndpoints replaces the path prefixes Logging skips. It is safe
+// to call while serving, e.g. from a config.Watcher subscription.
+func SetExcludedEndpoints(endpoints []string) {
+	endpoints = append([]string{}, endpoints...)
+	excludedEndpoints.Store(&endpoints)
+}
+
 // Function to check if the path is in the list of excluded endpoints
 func isExcludedPath(path string) bool {
-	for _, endpoint := range excludedEndpoints {
+	endpoints := defaultExcludedEndpoints
+	if p := excludedEndpoints.Load(); p != nil {
+		endpoints = *p
+	}
+
+	for _, endpoint := range endpoints {
 		if strings.HasPrefix(path, endpoint) {
 			return true
 		}
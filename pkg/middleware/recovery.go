@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Recovery recovers from panics in the following handlers, logs them with
+// their stack trace and reports an errors.InternalServerError. Register it
+// after HandleError so the error is rendered.
+func Recovery(log *zap.Logger) gin.HandlerFunc {
+	panics, _ := otel.Meter("github.com/smallbiznis/go-lib/pkg/middleware").Int64Counter(
+		"panics",
+		metric.WithDescription("Number of panics recovered from HTTP handlers"),
+	)
+
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			ctx := c.Request.Context()
+			fields := []zapcore.Field{
+				zap.Any("panic", p),
+				zap.String("http_method", c.Request.Method),
+				zap.String("http_url", c.Request.URL.Path),
+				zap.Stack("stack"),
+			}
+
+			span := trace.SpanFromContext(ctx)
+			if sc := span.SpanContext(); sc.IsValid() {
+				fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+				fields = append(fields, zap.String("span_id", sc.SpanID().String()))
+			}
+
+			log.Error("recovered from panic", fields...)
+
+			span.RecordError(fmt.Errorf("panic: %v", p), trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, "panic")
+
+			if panics != nil {
+				panics.Add(ctx, 1, metric.WithAttributes(
+					attribute.String("transport", "http"),
+					attribute.String("http.route", c.FullPath()),
+				))
+			}
+
+			c.Error(errors.InternalServerError("InternalServerError", "internal server error"))
+			c.Status(http.StatusInternalServerError)
+			c.Abort()
+		}()
+
+		c.Next()
+	}
+}
@@ -1,16 +1,42 @@
 package middleware
 
 import (
-	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"github.com/smallbiznis/go-lib/pkg/tenant"
 )
 
-func Tenant() gin.HandlerFunc {
+// Tenant resolves the tenant of the request with resolvers, tried in order,
+// and binds it to the request context for tenant.FromContext. Without
+// resolvers tenant.Default, the request host, is used.
+func Tenant(resolvers ...tenant.Resolver) gin.HandlerFunc {
+	resolver := tenant.Default()
+	if len(resolvers) > 0 {
+		resolver = tenant.Chain(resolvers...)
+	}
+
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
-		c.Request = c.Request.WithContext(context.WithValue(ctx, "tenant", c.Request.Host))
+		id, err := tenant.Resolve(ctx, resolver, &tenant.Request{
+			Host:   c.Request.Host,
+			Path:   c.Request.URL.Path,
+			Header: c.Request.Header,
+		})
+		if err != nil {
+			status := http.StatusInternalServerError
+			if e, ok := errors.From(err); ok {
+				status = e.Status()
+			}
+			c.Error(err)
+			c.Status(status)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.Bind(ctx, id))
 		c.Next()
 	}
 }
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/tenant"
+)
+
+type domainStore map[string]string
+
+func (s domainStore) LookupDomain(ctx context.Context, domain string) (string, error) {
+	if id, ok := s[domain]; ok {
+		return id, nil
+	}
+	return "", tenant.ErrTenantNotFound
+}
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		resolvers []tenant.Resolver
+		host      string
+		header    string
+		status    int
+		want      string
+	}{
+		{"default uses host", nil, "acme.io", "", http.StatusOK, "acme.io"},
+		{"default ignores header", nil, "acme.io", "other", http.StatusOK, "acme.io"},
+		{"header opt in", []tenant.Resolver{tenant.Header(tenant.HeaderTenantID)}, "acme.io", "other", http.StatusOK, "other"},
+		{"unknown domain falls through", []tenant.Resolver{tenant.CustomDomain(domainStore{}), tenant.Subdomain("example.com")}, "acme.example.com", "", http.StatusOK, "acme"},
+		{"unknown domain", []tenant.Resolver{tenant.CustomDomain(domainStore{})}, "unknown.io", "", http.StatusNotFound, ""},
+		{"unresolved", []tenant.Resolver{tenant.Header(tenant.HeaderTenantID)}, "acme.io", "", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			r := gin.New()
+			r.Use(Tenant(tt.resolvers...))
+			r.GET("/", func(c *gin.Context) {
+				got, _ = tenant.FromContext(c.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(tenant.HeaderTenantID, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, w.Code)
+			}
+			if got != tt.want {
+				t.Errorf("expected tenant %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 
 	otelpyroscope "github.com/grafana/otel-profiling-go"
-	"github.com/smallbiznis/go-lib/pkg/env"
+	"github.com/smallbiznis/go-lib/pkg/config"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -21,6 +21,7 @@ import (
 var (
 	Resource = fx.Module("otelcol.resource", fx.Options(
 		fx.Provide(
+			NewConfig,
 			InitResource,
 		),
 	))
@@ -29,25 +30,45 @@ var (
 		fx.Provide(
 			InitTraceProvider,
 		),
+		fx.Invoke(func(lc fx.Lifecycle, tp *sdktrace.TracerProvider) {
+			lc.Append(fx.StopHook(tp.Shutdown))
+		}),
 	))
 
 	MetricProvider = fx.Module("otelcol.metric", fx.Options(
 		fx.Provide(
 			InitMetricProvider,
 		),
+		fx.Invoke(func(lc fx.Lifecycle, mp *metric.MeterProvider) {
+			lc.Append(fx.StopHook(mp.Shutdown))
+		}),
 	))
 )
 
-func InitResource() (res *resource.Resource, err error) {
+// Config sets the service attributes of the OpenTelemetry resource.
+type Config struct {
+	ServiceName    string `env:"SERVICE_NAME" default:"example"`
+	ServiceVersion string `env:"SERVICE_VERSION" default:"1.0.0"`
+	// ServiceNamespace has always been read from SERVICE_NAME, unlike the
+	// logger's, and stays that way so existing resources don't change.
+	ServiceNamespace string `env:"SERVICE_NAME" default:"smallbiznis"`
+}
+
+// NewConfig reads the resource configuration from the environment.
+func NewConfig() (*Config, error) {
+	return config.Load[Config]()
+}
+
+func InitResource(cfg *Config) (res *resource.Resource, err error) {
 	ctx := context.Background()
 	extra, err := resource.New(ctx,
 		resource.WithOS(),
 		resource.WithProcess(),
 		resource.WithContainer(),
 		resource.WithAttributes(
-			semconv.ServiceName(env.Lookup("SERVICE_NAME", "example")),
-			semconv.ServiceVersion(env.Lookup("SERVICE_VERSION", "1.0.0")),
-			semconv.ServiceNamespace(env.Lookup("SERVICE_NAME", "smallbiznis")),
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(cfg.ServiceVersion),
+			semconv.ServiceNamespace(cfg.ServiceNamespace),
 		),
 	)
 	if err != nil {
@@ -127,11 +148,6 @@ func InitMetricProvider(resource *resource.Resource) (*metric.MeterProvider, err
 			metric.NewPeriodicReader(metricClient),
 		),
 	)
-	defer func() {
-		if err := mp.Shutdown(ctx); err != nil {
-			panic(err)
-		}
-	}()
 	otel.SetMeterProvider(mp)
 
 	return mp, nil
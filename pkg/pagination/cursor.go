@@ -0,0 +1,251 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or whose
+// signature doesn't match.
+var ErrInvalidCursor = errors.BadRequest("InvalidCursor", "cursor is invalid")
+
+// CursorPagination requests a page of keyset (cursor) pagination.
+type CursorPagination struct {
+	Size   int    `form:"pageSize" validate:"min=1,max=250"`
+	Cursor string `form:"cursor"`
+}
+
+// Keyset describes the ordering cursor pagination walks. Column must come
+// from a whitelist, it is used as an identifier in the query.
+type Keyset struct {
+	Column   string
+	IDColumn string
+	Desc     bool
+}
+
+func (k Keyset) idColumn() string {
+	if k.IDColumn == "" {
+		return "id"
+	}
+	return k.IDColumn
+}
+
+// CursorPage is a page of cursor pagination results.
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// CursorCodec signs and verifies opaque cursors and page tokens.
+type CursorCodec struct {
+	secret []byte
+}
+
+// NewCursorCodec returns a codec signing with secret, which must not be
+// empty or cursors could be forged.
+func NewCursorCodec(secret []byte) (*CursorCodec, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("cursor secret is required")
+	}
+	return &CursorCodec{secret: secret}, nil
+}
+
+type cursorValue struct {
+	Type  string `json:"t,omitempty"`
+	Value any    `json:"v"`
+}
+
+type cursor struct {
+	Keys     []cursorValue `json:"k"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// Encode returns the cursor pointing at the row with the given sort value
+// and id. backward cursors page towards the start of the result set.
+func (c *CursorCodec) Encode(value, id any, backward bool) string {
+	payload, _ := json.Marshal(cursor{
+		Keys:     []cursorValue{encodeValue(value), encodeValue(id)},
+		Backward: backward,
+	})
+	return c.seal(payload)
+}
+
+// Decode verifies s and returns the sort value and id it points at.
+func (c *CursorCodec) Decode(s string) (value, id any, backward bool, err error) {
+	payload, ok := c.open(s)
+	if !ok {
+		return nil, nil, false, ErrInvalidCursor
+	}
+
+	var cur cursor
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&cur); err != nil {
+		return nil, nil, false, errors.WithCause(ErrInvalidCursor, err)
+	}
+	if len(cur.Keys) != 2 {
+		return nil, nil, false, ErrInvalidCursor
+	}
+
+	if value, err = decodeValue(cur.Keys[0]); err != nil {
+		return nil, nil, false, errors.WithCause(ErrInvalidCursor, err)
+	}
+	if id, err = decodeValue(cur.Keys[1]); err != nil {
+		return nil, nil, false, errors.WithCause(ErrInvalidCursor, err)
+	}
+	return value, id, cur.Backward, nil
+}
+
+// seal encodes payload followed by its signature.
+func (c *CursorCodec) seal(payload []byte) string {
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+// open returns the payload of a sealed value if its signature matches.
+func (c *CursorCodec) open(s string) ([]byte, bool) {
+	p, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return nil, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, false
+	}
+	return payload, hmac.Equal(mac, c.sign(payload))
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+func encodeValue(v any) cursorValue {
+	switch t := v.(type) {
+	case time.Time:
+		return cursorValue{Type: "time", Value: t.Format(time.RFC3339Nano)}
+	case *time.Time:
+		if t == nil {
+			return cursorValue{}
+		}
+		return cursorValue{Type: "time", Value: t.Format(time.RFC3339Nano)}
+	}
+	return cursorValue{Value: v}
+}
+
+func decodeValue(v cursorValue) (any, error) {
+	if v.Type == "time" {
+		s, ok := v.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid time value %v", v.Value)
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	if n, ok := v.Value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return v.Value, nil
+}
+
+// Paginate returns a scope selecting Size+1 rows after (or before, for
+// backward cursors) the cursor, ordered by the keyset. The extra row tells
+// NewCursorPage whether another page exists.
+func (p CursorPagination) Paginate(codec *CursorCodec, ks Keyset) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		desc := ks.Desc
+		if p.Cursor != "" {
+			value, id, backward, err := codec.Decode(p.Cursor)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+
+			// walking backward flips the comparison and the order
+			op := ">"
+			if desc != backward {
+				op = "<"
+			}
+			desc = desc != backward
+
+			db = db.Where(fmt.Sprintf("(%s, %s) %s (?, ?)",
+				db.Statement.Quote(ks.Column), db.Statement.Quote(ks.idColumn()), op,
+			), value, id)
+		}
+
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+
+		db = db.Order(fmt.Sprintf("%s %s, %s %s",
+			db.Statement.Quote(ks.Column), direction, db.Statement.Quote(ks.idColumn()), direction,
+		))
+
+		if p.Size > 0 {
+			db = db.Limit(p.Size + 1)
+		}
+		return db
+	}
+}
+
+// NewCursorPage builds the page from the rows selected with Paginate. key
+// returns the sort value and id of an item.
+func NewCursorPage[T any](p CursorPagination, codec *CursorCodec, items []T, key func(T) (value, id any)) (*CursorPage[T], error) {
+	backward := false
+	if p.Cursor != "" {
+		var err error
+		if _, _, backward, err = codec.Decode(p.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	hasMore := p.Size > 0 && len(items) > p.Size
+	if hasMore {
+		items = items[:p.Size]
+	}
+
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	hasNext, hasPrev := hasMore, p.Cursor != ""
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if hasNext {
+		value, id := key(items[len(items)-1])
+		page.NextCursor = codec.Encode(value, id, false)
+	}
+	if hasPrev {
+		value, id := key(items[0])
+		page.PrevCursor = codec.Encode(value, id, true)
+	}
+
+	return page, nil
+}
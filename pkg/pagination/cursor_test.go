@@ -0,0 +1,119 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smallbiznis/go-lib/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type payment struct {
+	ID     int64
+	Amount int64
+}
+
+// seedPayments stores payments 1 to 7 with amounts that repeat so the id
+// breaks ties.
+func seedPayments(t *testing.T) *gorm.DB {
+	db := testdb.New(t, &payment{})
+	for i := int64(1); i <= 7; i++ {
+		if err := db.Create(&payment{ID: i, Amount: (i + 1) / 2 * 100}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func ids(items []payment) (out []int64) {
+	for _, item := range items {
+		out = append(out, item.ID)
+	}
+	return
+}
+
+func newTestCodec(t *testing.T, secret string) *CursorCodec {
+	codec, err := NewCursorCodec([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return codec
+}
+
+func TestCursorPagination(t *testing.T) {
+	db := seedPayments(t)
+	codec := newTestCodec(t, "secret")
+	ks := Keyset{Column: "amount", Desc: true}
+	key := func(i payment) (any, any) { return i.Amount, i.ID }
+
+	fetch := func(p CursorPagination) *CursorPage[payment] {
+		var items []payment
+		if err := db.Scopes(p.Paginate(codec, ks)).Find(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+		page, err := NewCursorPage(p, codec, items, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	first := fetch(CursorPagination{Size: 3})
+	if got := ids(first.Items); len(got) != 3 || got[0] != 7 || got[1] != 6 || got[2] != 5 {
+		t.Fatalf("unexpected first page %v", got)
+	}
+	if first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("unexpected cursors %+v", first)
+	}
+
+	second := fetch(CursorPagination{Size: 3, Cursor: first.NextCursor})
+	if got := ids(second.Items); len(got) != 3 || got[0] != 4 || got[1] != 3 || got[2] != 2 {
+		t.Fatalf("unexpected second page %v", got)
+	}
+
+	back := fetch(CursorPagination{Size: 3, Cursor: second.PrevCursor})
+	if got := ids(back.Items); len(got) != 3 || got[0] != 7 || got[2] != 5 {
+		t.Fatalf("unexpected previous page %v", got)
+	}
+	if back.PrevCursor != "" {
+		t.Errorf("expected no previous cursor on the first page")
+	}
+
+	last := fetch(CursorPagination{Size: 3, Cursor: second.NextCursor})
+	if got := ids(last.Items); len(got) != 1 || got[0] != 1 || last.NextCursor != "" {
+		t.Fatalf("unexpected last page %v", last)
+	}
+}
+
+func TestCursorTampered(t *testing.T) {
+	codec := newTestCodec(t, "secret")
+	cur := newTestCodec(t, "other").Encode(int64(100), int64(1), false)
+
+	if _, _, _, err := codec.Decode(cur); err == nil {
+		t.Error("expected cursor signed with another secret to be rejected")
+	}
+
+	p := CursorPagination{Size: 3, Cursor: cur}
+	if _, err := NewCursorPage(p, codec, []payment{{ID: 1}}, func(i payment) (any, any) { return i.Amount, i.ID }); err == nil {
+		t.Error("expected NewCursorPage to reject the cursor")
+	}
+}
+
+func TestCursorCodecRequiresSecret(t *testing.T) {
+	if _, err := NewCursorCodec(nil); err == nil {
+		t.Error("expected empty secret to be rejected")
+	}
+}
+
+func TestCursorNilTime(t *testing.T) {
+	codec := newTestCodec(t, "secret")
+
+	var at *time.Time
+	value, id, _, err := codec.Decode(codec.Encode(at, int64(1), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != nil || id != int64(1) {
+		t.Errorf("unexpected cursor %v %v", value, id)
+	}
+}
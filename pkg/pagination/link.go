@@ -0,0 +1,43 @@
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SetLinkHeaders writes the RFC 8288 Link header (first, prev, next, last)
+// and X-Total-Count for page, linking to u with the pageIndex and pageSize
+// query parameters replaced.
+func SetLinkHeaders[T any](h http.Header, u *url.URL, page *Page[T]) {
+	h.Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+
+	last := page.TotalPages
+	if last < 1 {
+		last = 1
+	}
+
+	links := []string{link(u, 1, page.Size, "first")}
+	if page.Page > 1 {
+		links = append(links, link(u, min(page.Page-1, last), page.Size, "prev"))
+	}
+	if page.HasNext {
+		links = append(links, link(u, page.Page+1, page.Size, "next"))
+	}
+	links = append(links, link(u, last, page.Size, "last"))
+
+	h.Set("Link", strings.Join(links, ", "))
+}
+
+func link(u *url.URL, page, size int, rel string) string {
+	target := *u
+	q := target.Query()
+	q.Set("pageIndex", strconv.Itoa(page))
+	if size > 0 {
+		q.Set("pageSize", strconv.Itoa(size))
+	}
+	target.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+}
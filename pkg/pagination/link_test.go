@@ -0,0 +1,26 @@
+package pagination
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestSetLinkHeaders(t *testing.T) {
+	u, _ := url.Parse("/invoices?status=paid&pageIndex=2&pageSize=10")
+
+	h := http.Header{}
+	SetLinkHeaders(h, u, NewPage([]int{}, Pagination{Size: 10, Page: 2}, 35))
+
+	if got := h.Get("X-Total-Count"); got != "35" {
+		t.Errorf("unexpected total count %q", got)
+	}
+
+	want := `</invoices?pageIndex=1&pageSize=10&status=paid>; rel="first", ` +
+		`</invoices?pageIndex=1&pageSize=10&status=paid>; rel="prev", ` +
+		`</invoices?pageIndex=3&pageSize=10&status=paid>; rel="next", ` +
+		`</invoices?pageIndex=4&pageSize=10&status=paid>; rel="last"`
+	if got := h.Get("Link"); got != want {
+		t.Errorf("unexpected link header\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -1,6 +1,17 @@
 package pagination
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidSort is returned when sort_by references a field that isn't
+// sortable.
+var ErrInvalidSort = errors.BadRequest("InvalidSort", "sort_by contains a field that can't be sorted on")
 
 type Pagination struct {
 	Size    int    `form:"pageSize" validate:"min=1,max=250"`
@@ -9,13 +20,134 @@ type Pagination struct {
 	OrderBy string `form:"order_by"`
 }
 
+// Columns whitelists the sortable fields, mapping the name accepted in
+// sort_by to the column it sorts on.
+type Columns map[string]string
+
+// SortField is a column and direction parsed from sort_by.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Offset returns the 1-based page offset.
+func (p Pagination) Offset() int {
+	if p.Page < 1 || p.Size < 1 {
+		return 0
+	}
+	return (p.Page - 1) * p.Size
+}
+
 func (p Pagination) Paginate() func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if p.Size > 0 {
-			db.Limit(p.Size)
+			db = db.Limit(p.Size)
+		}
+		return db.Offset(p.Offset())
+	}
+}
+
+// SortFields parses sort_by, e.g. "-created_at,name", into the columns it
+// maps to. Fields without a "-" prefix use order_by as their direction.
+func (p Pagination) SortFields(columns Columns) ([]SortField, error) {
+	if strings.TrimSpace(p.SortBy) == "" {
+		return nil, nil
+	}
+
+	defaultDesc := strings.EqualFold(p.OrderBy, "desc")
+
+	fields := make([]SortField, 0)
+	for _, name := range strings.Split(p.SortBy, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
 		}
 
-		offset := p.Page * p.Size
-		return db.Offset(offset)
+		desc := defaultDesc
+		switch name[0] {
+		case '-':
+			desc, name = true, name[1:]
+		case '+':
+			desc, name = false, name[1:]
+		}
+
+		column, ok := columns[name]
+		if !ok {
+			return nil, errors.WithDetails(ErrInvalidSort, map[string]any{
+				"field":   "sort_by",
+				"message": fmt.Sprintf("%s is not sortable", name),
+			})
+		}
+		fields = append(fields, SortField{Column: column, Desc: desc})
 	}
+	return fields, nil
+}
+
+// Sort returns a scope ordering by the whitelisted sort_by fields.
+func (p Pagination) Sort(columns Columns) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		fields, err := p.SortFields(columns)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		if len(fields) == 0 {
+			return db
+		}
+
+		orderBy := clause.OrderBy{}
+		for _, f := range fields {
+			orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{
+				Column: clause.Column{Name: f.Column},
+				Desc:   f.Desc,
+			})
+		}
+		return db.Order(orderBy)
+	}
+}
+
+// Page is a page of offset pagination results.
+type Page[T any] struct {
+	Items      []T   `json:"items"`
+	Page       int   `json:"page"`
+	Size       int   `json:"size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+}
+
+// NewPage
+func NewPage[T any](items []T, p Pagination, total int64) *Page[T] {
+	page := &Page[T]{
+		Items: items,
+		Page:  p.Page,
+		Size:  p.Size,
+		Total: total,
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if p.Size > 0 {
+		page.TotalPages = int((total + int64(p.Size) - 1) / int64(p.Size))
+	} else if total > 0 {
+		page.TotalPages = 1
+	}
+	page.HasNext = page.Page < page.TotalPages
+	return page
+}
+
+// Find counts the rows matched by db and selects the requested page,
+// sorted by the whitelisted sort_by fields.
+func Find[T any](db *gorm.DB, p Pagination, columns Columns) (*Page[T], error) {
+	var total int64
+	if err := db.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]T, 0)
+	if err := db.Session(&gorm.Session{}).Scopes(p.Sort(columns), p.Paginate()).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return NewPage(items, p, total), nil
 }
@@ -0,0 +1,44 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+)
+
+var paymentColumns = Columns{
+	"id":     "id",
+	"amount": "amount",
+}
+
+func TestFind(t *testing.T) {
+	db := seedPayments(t)
+
+	page, err := Find[payment](db, Pagination{Size: 3, Page: 1, SortBy: "-amount,id"}, paymentColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(page.Items); len(got) != 3 || got[0] != 7 || got[1] != 5 || got[2] != 6 {
+		t.Errorf("unexpected first page %v", got)
+	}
+	if page.Total != 7 || page.TotalPages != 3 || !page.HasNext {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	page, err = Find[payment](db, Pagination{Size: 3, Page: 3, SortBy: "id"}, paymentColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(page.Items); len(got) != 1 || got[0] != 7 || page.HasNext {
+		t.Errorf("unexpected last page %+v", page)
+	}
+}
+
+func TestSortRejectsUnknownFields(t *testing.T) {
+	db := seedPayments(t)
+
+	_, err := Find[payment](db, Pagination{Size: 3, Page: 1, SortBy: "amount;drop table payments"}, paymentColumns)
+	if !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+)
+
+const (
+	// DefaultPageSize is used when page_size is 0.
+	DefaultPageSize = 50
+	// MaxPageSize caps page_size, larger values are coerced.
+	MaxPageSize = 250
+)
+
+var (
+	// ErrInvalidPageToken is returned for page tokens that are malformed,
+	// tampered with or were issued for a different query.
+	ErrInvalidPageToken = errors.BadRequest("InvalidPageToken", "page_token is invalid")
+	// ErrInvalidPageSize is returned for negative page sizes.
+	ErrInvalidPageSize = errors.BadRequest("InvalidPageSize", "page_size must not be negative")
+)
+
+type pageToken struct {
+	Page   int    `json:"p"`
+	Size   int    `json:"s"`
+	Filter string `json:"f"`
+}
+
+// FromPageToken converts the AIP-158 page_size and page_token request fields
+// into a Pagination. filter holds every request field that shapes the result
+// set (filter, order_by, parent...), a token issued for other values is
+// rejected. The page size of a token wins over page_size so pages stay
+// aligned while paging.
+func FromPageToken(codec *CursorCodec, pageSize int32, token string, filter ...string) (Pagination, error) {
+	if pageSize < 0 {
+		return Pagination{}, ErrInvalidPageSize
+	}
+
+	size := int(pageSize)
+	switch {
+	case size == 0:
+		size = DefaultPageSize
+	case size > MaxPageSize:
+		size = MaxPageSize
+	}
+
+	if token == "" {
+		return Pagination{Size: size, Page: 1}, nil
+	}
+
+	t, err := codec.decodePageToken(token)
+	if err != nil {
+		return Pagination{}, err
+	}
+	if t.Filter != filterHash(filter) || t.Page < 1 || t.Size < 1 {
+		return Pagination{}, ErrInvalidPageToken
+	}
+
+	return Pagination{Size: t.Size, Page: t.Page}, nil
+}
+
+// NextPageToken returns the token of the page after p, or "" when p is the
+// last page.
+func NextPageToken[T any](codec *CursorCodec, page *Page[T], filter ...string) string {
+	if !page.HasNext {
+		return ""
+	}
+	return codec.encodePageToken(pageToken{
+		Page:   page.Page + 1,
+		Size:   page.Size,
+		Filter: filterHash(filter),
+	})
+}
+
+func (c *CursorCodec) encodePageToken(t pageToken) string {
+	payload, _ := json.Marshal(t)
+	return c.seal(payload)
+}
+
+func (c *CursorCodec) decodePageToken(s string) (t pageToken, err error) {
+	payload, ok := c.open(s)
+	if !ok {
+		return t, ErrInvalidPageToken
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return t, errors.WithCause(ErrInvalidPageToken, err)
+	}
+	return t, nil
+}
+
+func filterHash(filter []string) string {
+	h := sha256.New()
+	for _, f := range filter {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
@@ -0,0 +1,44 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+)
+
+func TestPageToken(t *testing.T) {
+	codec := newTestCodec(t, "secret")
+
+	p, err := FromPageToken(codec, 0, "", `status = "paid"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size != DefaultPageSize || p.Page != 1 {
+		t.Fatalf("unexpected pagination %+v", p)
+	}
+
+	token := NextPageToken(codec, NewPage([]int{}, Pagination{Size: 10, Page: 1}, 25), `status = "paid"`)
+	if token == "" {
+		t.Fatal("expected next page token")
+	}
+
+	p, err = FromPageToken(codec, 20, token, `status = "paid"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size != 10 || p.Page != 2 {
+		t.Errorf("unexpected pagination %+v", p)
+	}
+
+	if _, err := FromPageToken(codec, 10, token, `status = "void"`); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("expected token to be rejected for another filter, got %v", err)
+	}
+	if _, err := FromPageToken(codec, 10, token[:len(token)-2], `status = "paid"`); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("expected tampered token to be rejected, got %v", err)
+	}
+
+	last := NewPage([]int{}, Pagination{Size: 10, Page: 3}, 25)
+	if token := NextPageToken(codec, last); token != "" {
+		t.Errorf("expected no token after the last page, got %q", token)
+	}
+}
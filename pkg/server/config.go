@@ -0,0 +1,64 @@
+package server
+
+import (
+	"time"
+
+	"github.com/smallbiznis/go-lib/pkg/config"
+)
+
+// HTTPConfig configures the HTTP server built by NewServer.
+type HTTPConfig struct {
+	Addr              string        `env:"PORT" default:":8080"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" default:"30s"`
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"10s"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"120s"`
+	MaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" default:"1048576" validate:"gte=0"`
+	// ShutdownTimeout bounds how long in-flight requests are drained on stop.
+	ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" default:"15s"`
+}
+
+// GRPCConfig configures the gRPC server built by NewGrpcServer.
+type GRPCConfig struct {
+	Addr string `env:"GRPC_PORT" default:":4317"`
+	// Multiplex serves gRPC on the HTTP server listener instead of Addr.
+	Multiplex      bool            `env:"GRPC_MULTIPLEX" default:"false"`
+	MaxRecvMsgSize int             `env:"GRPC_MAX_RECV_MSG_SIZE" default:"4194304" validate:"gte=0"`
+	MaxSendMsgSize int             `env:"GRPC_MAX_SEND_MSG_SIZE" default:"4194304" validate:"gte=0"`
+	Keepalive      KeepaliveConfig `prefix:"GRPC_KEEPALIVE_"`
+}
+
+// GatewayConfig configures the grpc-gateway mounted by GatewayModule.
+type GatewayConfig struct {
+	// Prefix is the path the gateway is mounted under on the HTTP server.
+	// Paths under it without a gateway route fall through to the HTTP
+	// handler.
+	Prefix string `env:"GATEWAY_PREFIX" default:"/v1/" validate:"startswith=/"`
+}
+
+// KeepaliveConfig mirrors keepalive.ServerParameters and
+// keepalive.EnforcementPolicy. Zero values keep the grpc defaults.
+type KeepaliveConfig struct {
+	MaxConnectionIdle     time.Duration `env:"MAX_CONNECTION_IDLE"`
+	MaxConnectionAge      time.Duration `env:"MAX_CONNECTION_AGE"`
+	MaxConnectionAgeGrace time.Duration `env:"MAX_CONNECTION_AGE_GRACE"`
+	Time                  time.Duration `env:"TIME"`
+	Timeout               time.Duration `env:"TIMEOUT"`
+	MinTime               time.Duration `env:"MIN_TIME"`
+	PermitWithoutStream   bool          `env:"PERMIT_WITHOUT_STREAM"`
+}
+
+// NewHTTPConfig reads the HTTP server configuration from the environment.
+func NewHTTPConfig() (*HTTPConfig, error) {
+	return config.Load[HTTPConfig]()
+}
+
+// NewGRPCConfig reads the gRPC server configuration from the environment.
+func NewGRPCConfig() (*GRPCConfig, error) {
+	return config.Load[GRPCConfig]()
+}
+
+// NewGatewayConfig reads the grpc-gateway configuration from the environment.
+func NewGatewayConfig() (*GatewayConfig, error) {
+	return config.Load[GatewayConfig]()
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var GatewayModule = fx.Module("grpc.gateway", fx.Options(
+	fx.Provide(
+		NewGatewayConfig,
+		NewGateway,
+	),
+))
+
+// forwardedHeaders are passed to the gRPC server as metadata in addition to
+// the headers accepted by runtime.DefaultHeaderMatcher.
+var forwardedHeaders = []string{
+	"x-tenant-id",
+	"x-request-id",
+	"traceparent",
+	"tracestate",
+	"baggage",
+}
+
+// Gateway transcodes JSON/REST requests to the in-process *grpc.Server.
+// Services register their generated handlers against it, e.g.
+//
+//	pb.RegisterCustomerServiceHandler(ctx, gw.ServeMux, gw.Conn)
+type Gateway struct {
+	*runtime.ServeMux
+	Conn *grpc.ClientConn
+
+	prefix   string
+	fallback http.Handler
+}
+
+func NewGateway(lc fx.Lifecycle, cfg *GatewayConfig, server *grpc.Server) (*Gateway, error) {
+	lis := bufconn.Listen(1 << 20)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go server.Serve(lis)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			err := conn.Close()
+			if lerr := lis.Close(); err == nil {
+				err = lerr
+			}
+			return err
+		},
+	})
+
+	g := &Gateway{
+		Conn:   conn,
+		prefix: cfg.Prefix,
+	}
+	g.ServeMux = runtime.NewServeMux(
+		runtime.WithErrorHandler(gatewayErrorHandler),
+		runtime.WithRoutingErrorHandler(g.routingErrorHandler),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+	)
+	return g, nil
+}
+
+// Mount serves the gateway under its configured prefix and everything else
+// with h. Gateway routes take precedence under the prefix; requests the
+// gateway has no route for fall through to h, so handlers registered on h
+// under the same prefix keep working.
+func (g *Gateway) Mount(h http.Handler) http.Handler {
+	g.fallback = h
+
+	mux := http.NewServeMux()
+	mux.Handle(g.prefix, g.ServeMux)
+	mux.Handle("/", h)
+	return mux
+}
+
+func (g *Gateway) routingErrorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
+	if g.fallback != nil && (httpStatus == http.StatusNotFound || httpStatus == http.StatusMethodNotAllowed) {
+		g.fallback.ServeHTTP(w, r)
+		return
+	}
+	runtime.DefaultRoutingErrorHandler(ctx, mux, m, w, r, httpStatus)
+}
+
+func gatewayHeaderMatcher(key string) (string, bool) {
+	for _, header := range forwardedHeaders {
+		if strings.EqualFold(key, header) {
+			return header, true
+		}
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayErrorHandler writes gRPC errors using the same envelope as
+// middleware.HandleError.
+func gatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(errors.HTTPFromCode(st.Code()))
+	json.NewEncoder(w).Encode(gin.H{
+		"error": errors.FromStatus(st),
+	})
+}
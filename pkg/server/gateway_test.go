@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGateway(t *testing.T) {
+	var gw *Gateway
+	app := fx.New(
+		fx.Provide(func() *grpc.Server {
+			s := grpc.NewServer()
+			healthpb.RegisterHealthServer(s, health.NewServer())
+			return s
+		}),
+		GatewayModule,
+		fx.Populate(&gw),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Stop(context.Background())
+
+	client := healthpb.NewHealthClient(gw.Conn)
+	err := gw.HandlePath(http.MethodGet, "/v1/health/{service}", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		_, m := runtime.MarshalerForRequest(gw.ServeMux, r)
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: params["service"]})
+		if err != nil {
+			runtime.HTTPError(ctx, gw.ServeMux, m, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, gw.ServeMux, m, w, r, resp)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/v1/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	h := gw.Mount(engine)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{"grpc method", "/v1/health/", http.StatusOK, `"SERVING"`},
+		{"grpc error", "/v1/health/unknown", http.StatusNotFound, `"name":"NotFound"`},
+		{"gin route under prefix", "/v1/ping", http.StatusOK, "pong"},
+		{"unknown route", "/v1/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Fatalf("body = %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
-	"github.com/smallbiznis/go-lib/pkg/env"
+	"github.com/smallbiznis/go-lib/pkg/errors"
 	"github.com/smallbiznis/go-lib/pkg/otelcol"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel"
@@ -18,6 +18,7 @@ import (
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -27,15 +28,20 @@ var (
 		otelcol.TraceProvider,
 		otelcol.MetricProvider,
 		fx.Provide(
+			NewGRPCConfig,
 			NewServerOption,
 			NewGrpcServer,
 		),
 	))
 	GrpcServerInvoke = fx.Module("grpc.invoke", fx.Options(
-		fx.Invoke(func(lc fx.Lifecycle, server *grpc.Server) {
+		fx.Invoke(func(lc fx.Lifecycle, cfg *GRPCConfig, server *grpc.Server) {
 			lc.Append(fx.Hook{
 				OnStart: func(ctx context.Context) error {
-					lis, err := net.Listen("tcp", env.Lookup("GRPC_PORT", ":4317"))
+					if cfg.Multiplex {
+						// served by the HTTP server listener
+						return nil
+					}
+					lis, err := net.Listen("tcp", cfg.Addr)
 					if err != nil {
 						return err
 					}
@@ -43,6 +49,11 @@ var (
 					return nil
 				},
 				OnStop: func(ctx context.Context) error {
+					if cfg.Multiplex {
+						// handler based transports don't support graceful drain
+						server.Stop()
+						return nil
+					}
 					server.GracefulStop()
 					return nil
 				},
@@ -94,7 +105,7 @@ func InterceptorLogger(l *zap.Logger) logging.Logger {
 		case logging.LevelError:
 			logger.Error(msg)
 		default:
-			panic(fmt.Sprintf("unknown level %v", lvl))
+			logger.With(zap.Int("level", int(lvl))).Warn(msg)
 		}
 	})
 }
@@ -144,13 +155,17 @@ func NewServerOption(
 ) (options []grpc.ServerOption) {
 
 	options = []grpc.ServerOption{
-		grpc.UnaryInterceptor(TraceInterceptor),
 		grpc.ChainUnaryInterceptor(
+			RecoveryUnaryServerInterceptor(zap.L()),
+			// convert errors before logging so the logged code matches the response
+			errors.UnaryServerInterceptor(),
 			TraceInterceptor,
 			validator.UnaryServerInterceptor(validator.WithFailFast()),
 			logging.UnaryServerInterceptor(InterceptorLogger(zap.L())),
 		),
 		grpc.ChainStreamInterceptor(
+			RecoveryStreamServerInterceptor(zap.L()),
+			errors.StreamServerInterceptor(),
 			validator.StreamServerInterceptor(validator.WithFailFast()),
 			logging.StreamServerInterceptor(InterceptorLogger(zap.L())),
 		),
@@ -165,6 +180,25 @@ func NewServerOption(
 	return
 }
 
-func NewGrpcServer(opts ...grpc.ServerOption) *grpc.Server {
+func NewGrpcServer(cfg *GRPCConfig, opts []grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     cfg.Keepalive.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.Keepalive.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.Keepalive.MaxConnectionAgeGrace,
+			Time:                  cfg.Keepalive.Time,
+			Timeout:               cfg.Keepalive.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.Keepalive.MinTime,
+			PermitWithoutStream: cfg.Keepalive.PermitWithoutStream,
+		}),
+	)
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgSize))
+	}
 	return grpc.NewServer(opts...)
 }
@@ -3,15 +3,11 @@ package server
 import (
 	"testing"
 
-	"github.com/smallbiznis/go-lib/pkg/otelcol"
 	"go.uber.org/fx"
 )
 
 func TestGrpcServer(t *testing.T) {
 	if err := fx.New(
-		otelcol.Resource,
-		otelcol.TraceProvider,
-		otelcol.MetricProvider,
 		GrpcServerProvider,
 		GrpcServerInvoke,
 	).Err(); err != nil {
@@ -2,32 +2,52 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
-	"os"
 
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
-var (
-	port = ":8080"
-)
-
-func init() {
-	newPort, ok := os.LookupEnv("PORT")
-	if ok {
-		port = newPort
-	}
-}
-
 var Module = fx.Module("http.server", fx.Options(
 	fx.Provide(
-		NewServer,
+		NewHTTPConfig,
+		provideServer,
 	),
 ))
 
+var HttpServerInvoke = fx.Module("http.invoke", fx.Options(
+	fx.Invoke(func(lc fx.Lifecycle, shutdowner fx.Shutdowner, cfg *HTTPConfig, srv IServer) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				lis, err := srv.Listen()
+				if err != nil {
+					return err
+				}
+				go func() {
+					if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						zap.L().With(zap.Error(err)).Error("http server stopped unexpectedly")
+						shutdowner.Shutdown(fx.ExitCode(1))
+					}
+				}()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				ctx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+				defer cancel()
+
+				return srv.Down(ctx)
+			},
+		})
+	}),
+))
+
 type IServer interface {
 	RunTLS(string, string) error
 	Run() error
+	Listen() (net.Listener, error)
+	Serve(net.Listener) error
 	Down(ctx context.Context) error
 }
 
@@ -35,10 +55,15 @@ type server struct {
 	*http.Server
 }
 
-func NewServer(h http.Handler) IServer {
+func NewServer(cfg *HTTPConfig, h http.Handler) IServer {
 	srv := &http.Server{
-		Addr:    port,
-		Handler: h,
+		Addr:              cfg.Addr,
+		Handler:           h,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 	return &server{Server: srv}
 }
@@ -53,7 +78,18 @@ func (s *server) Run() (err error) {
 	return s.ListenAndServe()
 }
 
-// Down
+// Listen binds the configured address without serving, so bind errors
+// surface before the server is started in the background.
+func (s *server) Listen() (net.Listener, error) {
+	return net.Listen("tcp", s.Addr)
+}
+
+// Down gracefully drains in-flight requests until ctx expires, then closes
+// any connections that are still open.
 func (s *server) Down(ctx context.Context) (err error) {
-	return s.Shutdown(ctx)
+	if err = s.Shutdown(ctx); errors.Is(err, context.DeadlineExceeded) {
+		zap.L().Warn("http server drain timeout exceeded, closing remaining connections")
+		return s.Close()
+	}
+	return err
 }
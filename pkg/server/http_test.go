@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+func TestHttpServer(t *testing.T) {
+	app := fx.New(
+		fx.Provide(func() http.Handler {
+			return http.NewServeMux()
+		}),
+		Module,
+		HttpServerInvoke,
+		fx.Decorate(func(cfg *HTTPConfig) *HTTPConfig {
+			cfg.Addr = "127.0.0.1:0"
+			cfg.ShutdownTimeout = time.Second
+			return cfg
+		}),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+type serverParams struct {
+	fx.In
+
+	Config     *HTTPConfig
+	Handler    http.Handler
+	GRPCConfig *GRPCConfig  `optional:"true"`
+	GRPCServer *grpc.Server `optional:"true"`
+	Gateway    *Gateway     `optional:"true"`
+}
+
+// provideServer builds the HTTP server, mounting the grpc-gateway when
+// GatewayModule is used and routing gRPC traffic to the *grpc.Server on the
+// same listener when GRPCConfig.Multiplex is set.
+func provideServer(p serverParams) IServer {
+	h := p.Handler
+	if p.Gateway != nil {
+		h = p.Gateway.Mount(h)
+	}
+	if p.GRPCConfig != nil && p.GRPCConfig.Multiplex && p.GRPCServer != nil {
+		h = Multiplex(p.GRPCServer, h)
+	}
+	return NewServer(p.Config, h)
+}
+
+// Multiplex serves gRPC and plain HTTP from a single handler. HTTP/2
+// requests with an application/grpc content type are handed to the gRPC
+// server, everything else goes to h. Cleartext HTTP/2 (h2c) is accepted so
+// gRPC clients can connect without TLS.
+//
+// The HTTP server's ReadTimeout and WriteTimeout are cleared for gRPC
+// requests so long-lived streams are not cut off; use gRPC deadlines instead.
+func Multiplex(grpcServer *grpc.Server, h http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGrpcRequest(r) {
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(time.Time{})
+			_ = rc.SetWriteDeadline(time.Time{})
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+func isGrpcRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryServerInterceptor recovers from panics in unary handlers and
+// returns codes.Internal.
+func RecoveryUnaryServerInterceptor(l *zap.Logger) grpc.UnaryServerInterceptor {
+	return recovery.UnaryServerInterceptor(recovery.WithRecoveryHandlerContext(recoveryHandler(l)))
+}
+
+// RecoveryStreamServerInterceptor recovers from panics in stream handlers and
+// returns codes.Internal.
+func RecoveryStreamServerInterceptor(l *zap.Logger) grpc.StreamServerInterceptor {
+	return recovery.StreamServerInterceptor(recovery.WithRecoveryHandlerContext(recoveryHandler(l)))
+}
+
+func recoveryHandler(l *zap.Logger) recovery.RecoveryHandlerFuncContext {
+	panics, _ := otel.Meter("github.com/smallbiznis/go-lib/pkg/server").Int64Counter(
+		"panics",
+		metric.WithDescription("Number of panics recovered from gRPC handlers"),
+	)
+
+	return func(ctx context.Context, p any) error {
+		fields := []zapcore.Field{
+			zap.Any("panic", p),
+			zap.Stack("stack"),
+		}
+
+		method, _ := grpc.Method(ctx)
+		if method != "" {
+			fields = append(fields, zap.String("grpc_method", method))
+		}
+
+		span := trace.SpanFromContext(ctx)
+		if sc := span.SpanContext(); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+			fields = append(fields, zap.String("span_id", sc.SpanID().String()))
+		}
+
+		l.Error("recovered from panic", fields...)
+
+		span.RecordError(fmt.Errorf("panic: %v", p), trace.WithStackTrace(true))
+		span.SetStatus(otelcodes.Error, "panic")
+
+		if panics != nil {
+			panics.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("transport", "grpc"),
+				attribute.String("rpc.method", method),
+			))
+		}
+
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
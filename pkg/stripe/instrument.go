@@ -0,0 +1,226 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"time"
+
+	st "github.com/stripe/stripe-go/v80"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/smallbiznis/go-lib/pkg/stripe"
+
+// kind tells reads and writes from creates, the POSTs that make a new
+// object and get an idempotency key.
+type kind int
+
+const (
+	read kind = iota
+	write
+	create
+)
+
+// instruments holds the tracer and meters shared by every call.
+type instruments struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+func newInstruments(tp trace.TracerProvider, mp metric.MeterProvider) (*instruments, error) {
+	meter := mp.Meter(instrumentationName)
+
+	duration, err := meter.Float64Histogram(
+		"stripe.client.request.duration",
+		metric.WithDescription("Duration of Stripe API calls, retries included"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := meter.Int64Counter(
+		"stripe.client.request.errors",
+		metric.WithDescription("Number of failed Stripe API calls"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instruments{
+		tracer:   tp.Tracer(instrumentationName),
+		duration: duration,
+		errors:   errs,
+	}, nil
+}
+
+// call runs fn in a span. Creates get an idempotency key that fn passes to
+// withContext and that is reused across the retries of this call only.
+// Failures are retried up to the configured number of times when retryable
+// allows it.
+func call[T any](ctx context.Context, s *stripe, k kind, method, id string, fn func(ctx context.Context, key *string) (T, error)) (T, error) {
+	ctx, span := s.tracer.Start(ctx, "stripe."+method, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	var key *string
+	if k == create {
+		key = st.String(st.NewIdempotencyKey())
+	}
+
+	var (
+		start  = time.Now()
+		result T
+		err    error
+	)
+	for attempt := 0; ; attempt++ {
+		hint := new(string)
+		result, err = fn(context.WithValue(ctx, shouldRetryKey{}, hint), key)
+		if err == nil || attempt >= s.maxRetries || !retryable(ctx, err, *hint) {
+			break
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("stripe.attempt", attempt+1),
+			attribute.String("error", err.Error()),
+		))
+		if sleepErr := sleep(ctx, backoff(s.retryBackoff, attempt)); sleepErr != nil {
+			break
+		}
+	}
+
+	objectID, requestID := resource(result)
+	if objectID == "" {
+		objectID = id
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("stripe.method", method),
+	}
+	span.SetAttributes(attrs...)
+	if objectID != "" {
+		span.SetAttributes(attribute.String("stripe.object_id", objectID))
+	}
+
+	var stripeErr *st.Error
+	if errors.As(err, &stripeErr) {
+		requestID = stripeErr.RequestID
+		attrs = append(attrs, attribute.Int("http.response.status_code", stripeErr.HTTPStatusCode))
+		span.SetAttributes(
+			attribute.String("stripe.error.type", string(stripeErr.Type)),
+			attribute.String("stripe.error.code", string(stripeErr.Code)),
+		)
+	}
+	if requestID != "" {
+		span.SetAttributes(attribute.String("stripe.request_id", requestID))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+	attrs = append(attrs, attribute.Bool("error", err != nil))
+	s.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+
+	return result, err
+}
+
+// shouldRetryKey carries the *string receiving the Stripe-Should-Retry
+// response header of an attempt.
+type shouldRetryKey struct{}
+
+// retryable follows the Stripe-Should-Retry header when it is set.
+// Otherwise network errors, 409s, 429s and 503s are retried, unless ctx is
+// done. Other 5xx are not: Stripe sets Stripe-Should-Retry when retrying
+// them is safe.
+func retryable(ctx context.Context, err error, shouldRetry string) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var stripeErr *st.Error
+	if !errors.As(err, &stripeErr) {
+		return true
+	}
+
+	switch shouldRetry {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	switch stripeErr.HTTPStatusCode {
+	case http.StatusConflict, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// withContext returns a copy of params, which may be nil, carrying ctx and
+// key unless the caller set its own idempotency key. The caller's params
+// are left untouched.
+func withContext[P any, PP interface {
+	*P
+	st.ParamsContainer
+}](params PP, ctx context.Context, key *string) PP {
+	p := PP(new(P))
+	if params != nil {
+		*p = *params
+	}
+
+	sp := p.GetParams()
+	sp.Context = ctx
+	if sp.IdempotencyKey == nil {
+		sp.IdempotencyKey = key
+	}
+	return p
+}
+
+// backoff doubles base with every attempt, with 25% jitter.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	if d <= 0 {
+		return 0
+	}
+	return d - time.Duration(rand.Int63n(int64(d)/4+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// resource returns the ID and the Stripe request id of a returned object.
+func resource(v any) (id, requestID string) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return "", ""
+	}
+	rv = rv.Elem()
+
+	if f := rv.FieldByName("ID"); f.IsValid() && f.Kind() == reflect.String {
+		id = f.String()
+	}
+	if f := rv.FieldByName("LastResponse"); f.IsValid() && !f.IsNil() {
+		if resp, ok := f.Interface().(*st.APIResponse); ok {
+			requestID = resp.RequestID
+		}
+	}
+	return id, requestID
+}
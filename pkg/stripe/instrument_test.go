@@ -0,0 +1,218 @@
+package stripe
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	st "github.com/stripe/stripe-go/v80"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCallRetriesAndInstruments(t *testing.T) {
+	var keys []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		if len(keys) == 1 {
+			w.Header().Set("Request-Id", "req_limited")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"type":"invalid_request_error","code":"lock_timeout","message":"Too many requests"}}`))
+			return
+		}
+		w.Header().Set("Request-Id", "req_ok")
+		w.Write([]byte(`{"id":"cus_123","object":"customer"}`))
+	})
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	client, err := New(
+		&Config{APIKey: "sk_test_key", BackendURL: srv.URL, MaxRetries: 2, RetryBackoff: time.Millisecond},
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := &st.CustomerParams{Email: st.String("a@example.com")}
+	if _, err := client.CreateCustomer(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected one idempotency key across retries, got %v", keys)
+	}
+	if params.IdempotencyKey != nil || params.Context != nil {
+		t.Errorf("expected caller params to be left untouched, got %+v", params.Params)
+	}
+
+	if _, err := client.CreateCustomer(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[2] == keys[0] {
+		t.Errorf("expected a new idempotency key for a new call, got %v", keys)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 2 || ended[0].Name() != "stripe.CreateCustomer" {
+		t.Fatalf("unexpected spans %v", ended)
+	}
+	attrs := map[attribute.Key]string{}
+	for _, kv := range ended[0].Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	if attrs["stripe.method"] != "CreateCustomer" || attrs["stripe.object_id"] != "cus_123" || attrs["stripe.request_id"] != "req_ok" {
+		t.Errorf("unexpected span attributes %v", attrs)
+	}
+	if len(ended[0].Events()) != 1 || ended[0].Events()[0].Name != "retry" {
+		t.Errorf("expected a retry event, got %v", ended[0].Events())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "stripe.client.request.duration" {
+				found = m.Unit == "s" && len(m.Data.(metricdata.Histogram[float64]).DataPoints) > 0
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a duration data point")
+	}
+}
+
+func TestCallKeysOnlyCreates(t *testing.T) {
+	keys := map[string][]string{}
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		keys[call] = append(keys[call], r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		if len(keys[call]) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"type":"api_error","message":"unavailable"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"sub_123","object":"subscription"}`))
+	})
+
+	client, err := newTestClient(&Config{APIKey: "sk_test_key", BackendURL: srv.URL, MaxRetries: 1, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.CreateSubscription(ctx, &st.SubscriptionParams{Customer: st.String("cus_123")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CancelSubscription(ctx, "sub_123", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteCustomer(ctx, "cus_123", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// stripe-go gives every write attempt without a key a fresh one
+	for call, got := range keys {
+		same := len(got) == 2 && got[0] == got[1]
+		if create := call == "POST /v1/subscriptions"; same != create {
+			t.Errorf("%s: unexpected idempotency keys %q", call, got)
+		}
+	}
+	if len(keys) != 3 {
+		t.Errorf("unexpected calls %v", keys)
+	}
+}
+
+func TestCallDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Request-Id", "req_missing")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","code":"resource_missing","message":"No such customer"}}`))
+	})
+
+	spans := tracetest.NewSpanRecorder()
+	client, err := New(
+		&Config{APIKey: "sk_test_key", BackendURL: srv.URL, MaxRetries: 2, RetryBackoff: time.Millisecond},
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		sdkmetric.NewMeterProvider(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetCustomer(context.Background(), "cus_missing", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected no retry, got %d calls", calls)
+	}
+
+	span := spans.Ended()[0]
+	attrs := map[attribute.Key]string{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	if attrs["stripe.object_id"] != "cus_missing" || attrs["stripe.request_id"] != "req_missing" || attrs["stripe.error.code"] != "resource_missing" {
+		t.Errorf("unexpected span attributes %v", attrs)
+	}
+}
+
+func TestCallRetryRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		code        string
+		shouldRetry string
+		calls       int
+	}{
+		{"rate limit", http.StatusTooManyRequests, "rate_limit", "", 3},
+		{"rate limit told not to retry", http.StatusTooManyRequests, "rate_limit", "false", 1},
+		{"lock timeout", http.StatusTooManyRequests, "lock_timeout", "", 3},
+		{"conflict", http.StatusConflict, "", "", 3},
+		{"unavailable", http.StatusServiceUnavailable, "", "", 3},
+		{"server error", http.StatusInternalServerError, "", "", 1},
+		{"unavailable told not to retry", http.StatusServiceUnavailable, "", "false", 1},
+		{"server error told to retry", http.StatusInternalServerError, "", "true", 3},
+		{"client error told to retry", http.StatusBadRequest, "", "true", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				if tt.shouldRetry != "" {
+					w.Header().Set("Stripe-Should-Retry", tt.shouldRetry)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"error":{"type":"api_error","code":"` + tt.code + `","message":"failed"}}`))
+			})
+
+			client, err := newTestClient(&Config{APIKey: "sk_test_key", BackendURL: srv.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.GetCustomer(context.Background(), "cus_123", nil); err == nil {
+				t.Fatal("expected error")
+			}
+			if calls != tt.calls {
+				t.Errorf("expected %d calls, got %d", tt.calls, calls)
+			}
+		})
+	}
+}
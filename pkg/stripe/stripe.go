@@ -3,22 +3,51 @@ package stripe
 import (
 	"context"
 	"errors"
+	"net/http"
+	"time"
 
-	"github.com/smallbiznis/go-lib/pkg/env"
+	"github.com/smallbiznis/go-lib/pkg/config"
 	st "github.com/stripe/stripe-go/v80"
-	"github.com/stripe/stripe-go/v80/customer"
-	"github.com/stripe/stripe-go/v80/subscription"
+	"github.com/stripe/stripe-go/v80/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 )
 
 var (
 	Stripe = fx.Module("stripe", fx.Options(
-		fx.Provide(New),
+		fx.Provide(
+			NewConfig,
+			provideStripe,
+		),
 	))
 )
 
-func init() {
-	st.Key = env.Lookup("STRIPE_SECRET_KEY", "sk_test_51LlDyWCIG3hXfWuZUvt4U1eu4ywFzTPj8eWePweMnXH9Bx96L1kCWbfmeFL0VsUI2TKgUUALxztYOcvbnHHyLyFB00fY5bYD4W")
+// ErrMissingAPIKey is returned by New when no secret key is configured.
+var ErrMissingAPIKey = errors.New("stripe: api key is required")
+
+// Config configures the Stripe client built by New.
+type Config struct {
+	APIKey string `env:"STRIPE_SECRET_KEY" required:"true" secret:"true"`
+	// Account is sent as Stripe-Account to act on behalf of a Connect
+	// account, unless a call sets its own.
+	Account string `env:"STRIPE_ACCOUNT"`
+	// APIVersion overrides the Stripe-Version pinned by stripe-go.
+	APIVersion string `env:"STRIPE_API_VERSION"`
+	// BackendURL overrides the API URL, e.g. to point at stripe-mock.
+	BackendURL string `env:"STRIPE_API_URL" validate:"omitempty,url"`
+	// MaxRetries is how many times failures Stripe marks as safe to retry,
+	// network errors, conflicts, rate limits and 503s are retried.
+	MaxRetries   int           `env:"STRIPE_MAX_RETRIES" default:"2" validate:"gte=0"`
+	RetryBackoff time.Duration `env:"STRIPE_RETRY_BACKOFF" default:"500ms"`
+}
+
+// NewConfig reads the Stripe configuration from the environment.
+func NewConfig() (*Config, error) {
+	return config.Load[Config]()
 }
 
 type IStripe interface {
@@ -26,7 +55,19 @@ type IStripe interface {
 	GetCustomer(context.Context, string, *st.CustomerParams) (*st.Customer, error)
 	DeleteCustomer(context.Context, string, *st.CustomerParams) error
 
-	CreateBillingSession(context.Context, *st.BillingPortalSession) (*st.BillingPortalSession, error)
+	CreateBillingSession(context.Context, *st.BillingPortalSessionParams) (*st.BillingPortalSession, error)
+
+	// CreateCheckoutSession starts a Checkout Session. Set Mode to
+	// subscription to start a subscription or to payment for a one-off
+	// payment.
+	CreateCheckoutSession(context.Context, *st.CheckoutSessionParams) (*st.CheckoutSession, error)
+	GetCheckoutSession(context.Context, string, *st.CheckoutSessionParams) (*st.CheckoutSession, error)
+	ExpireCheckoutSession(context.Context, string, *st.CheckoutSessionExpireParams) (*st.CheckoutSession, error)
+
+	CreatePortalConfiguration(context.Context, *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error)
+	GetPortalConfiguration(context.Context, string, *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error)
+	UpdatePortalConfiguration(context.Context, string, *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error)
+	ListPortalConfigurations(context.Context, *st.BillingPortalConfigurationListParams) ([]*st.BillingPortalConfiguration, error)
 
 	CreateSubscription(context.Context, *st.SubscriptionParams) (*st.Subscription, error)
 	GetSubscription(context.Context, string, *st.SubscriptionParams) (*st.Subscription, error)
@@ -34,46 +75,204 @@ type IStripe interface {
 	CancelSubscription(context.Context, string, *st.SubscriptionCancelParams) error
 }
 
-type stripe struct{}
+type stripe struct {
+	*instruments
+
+	api          *client.API
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+type params struct {
+	fx.In
+
+	Config         *Config
+	TracerProvider *sdktrace.TracerProvider `optional:"true"`
+	MeterProvider  *sdkmetric.MeterProvider `optional:"true"`
+}
+
+// provideStripe builds the client, falling back to the global OpenTelemetry
+// providers when otelcol isn't used.
+func provideStripe(p params) (IStripe, error) {
+	var tp trace.TracerProvider = otel.GetTracerProvider()
+	if p.TracerProvider != nil {
+		tp = p.TracerProvider
+	}
+
+	var mp metric.MeterProvider = otel.GetMeterProvider()
+	if p.MeterProvider != nil {
+		mp = p.MeterProvider
+	}
+
+	return New(p.Config, tp, mp)
+}
+
+// New builds an IStripe backed by its own client.API, so services can hold
+// clients for several accounts side by side. Every call is traced through
+// tp and measured through mp.
+func New(cfg *Config, tp trace.TracerProvider, mp metric.MeterProvider) (IStripe, error) {
+	if cfg.APIKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	inst, err := newInstruments(tp, mp)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &st.BackendConfig{
+		HTTPClient: &http.Client{
+			Timeout: 80 * time.Second,
+			Transport: &headerTransport{
+				base:    http.DefaultTransport,
+				account: cfg.Account,
+				version: cfg.APIVersion,
+			},
+		},
+		// Retries are done by call so they are traced and the retries of a
+		// create share an idempotency key.
+		MaxNetworkRetries: st.Int64(0),
+	}
+	if cfg.BackendURL != "" {
+		backend.URL = st.String(cfg.BackendURL)
+	}
 
-func New() IStripe {
-	return &stripe{}
+	return &stripe{
+		instruments:  inst,
+		api:          client.New(cfg.APIKey, st.NewBackendsWithConfig(backend)),
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+	}, nil
 }
 
-func (s *stripe) CreateCustomer(ctx context.Context, req *st.CustomerParams) (*st.Customer, error) {
-	return customer.New(req)
+// headerTransport sets the Stripe-Account and Stripe-Version headers
+// configured on the client and hands the Stripe-Should-Retry response header
+// to call.
+type headerTransport struct {
+	base    http.RoundTripper
+	account string
+	version string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.account != "" || t.version != "" {
+		req = req.Clone(req.Context())
+		if t.account != "" && req.Header.Get("Stripe-Account") == "" {
+			req.Header.Set("Stripe-Account", t.account)
+		}
+		if t.version != "" {
+			req.Header.Set("Stripe-Version", t.version)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		if hint, ok := req.Context().Value(shouldRetryKey{}).(*string); ok {
+			*hint = resp.Header.Get("Stripe-Should-Retry")
+		}
+	}
+	return resp, err
+}
+
+func (s *stripe) CreateCustomer(ctx context.Context, params *st.CustomerParams) (*st.Customer, error) {
+	return call(ctx, s, create, "CreateCustomer", "", func(ctx context.Context, key *string) (*st.Customer, error) {
+		return s.api.Customers.New(withContext(params, ctx, key))
+	})
 }
 
 func (s *stripe) GetCustomer(ctx context.Context, id string, params *st.CustomerParams) (*st.Customer, error) {
-	return customer.Get(id, params)
+	return call(ctx, s, read, "GetCustomer", id, func(ctx context.Context, key *string) (*st.Customer, error) {
+		return s.api.Customers.Get(id, withContext(params, ctx, key))
+	})
 }
 
 func (s *stripe) DeleteCustomer(ctx context.Context, id string, params *st.CustomerParams) error {
-	if _, err := customer.Del(id, params); err != nil {
-		return err
-	}
-	return nil
+	_, err := call(ctx, s, write, "DeleteCustomer", id, func(ctx context.Context, key *string) (*st.Customer, error) {
+		return s.api.Customers.Del(id, withContext(params, ctx, key))
+	})
+	return err
+}
+
+func (s *stripe) CreateBillingSession(ctx context.Context, params *st.BillingPortalSessionParams) (*st.BillingPortalSession, error) {
+	return call(ctx, s, create, "CreateBillingSession", "", func(ctx context.Context, key *string) (*st.BillingPortalSession, error) {
+		return s.api.BillingPortalSessions.New(withContext(params, ctx, key))
+	})
 }
 
-func (s *stripe) CreateBillingSession(ctx context.Context, req *st.BillingPortalSession) (*st.BillingPortalSession, error) {
-	return nil, errors.New("unimplement")
+func (s *stripe) CreateCheckoutSession(ctx context.Context, params *st.CheckoutSessionParams) (*st.CheckoutSession, error) {
+	return call(ctx, s, create, "CreateCheckoutSession", "", func(ctx context.Context, key *string) (*st.CheckoutSession, error) {
+		return s.api.CheckoutSessions.New(withContext(params, ctx, key))
+	})
 }
 
-func (s *stripe) CreateSubscription(ctx context.Context, req *st.SubscriptionParams) (*st.Subscription, error) {
-	return subscription.New(req)
+func (s *stripe) GetCheckoutSession(ctx context.Context, id string, params *st.CheckoutSessionParams) (*st.CheckoutSession, error) {
+	return call(ctx, s, read, "GetCheckoutSession", id, func(ctx context.Context, key *string) (*st.CheckoutSession, error) {
+		return s.api.CheckoutSessions.Get(id, withContext(params, ctx, key))
+	})
+}
+
+func (s *stripe) ExpireCheckoutSession(ctx context.Context, id string, params *st.CheckoutSessionExpireParams) (*st.CheckoutSession, error) {
+	return call(ctx, s, write, "ExpireCheckoutSession", id, func(ctx context.Context, key *string) (*st.CheckoutSession, error) {
+		return s.api.CheckoutSessions.Expire(id, withContext(params, ctx, key))
+	})
+}
+
+func (s *stripe) CreatePortalConfiguration(ctx context.Context, params *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error) {
+	return call(ctx, s, create, "CreatePortalConfiguration", "", func(ctx context.Context, key *string) (*st.BillingPortalConfiguration, error) {
+		return s.api.BillingPortalConfigurations.New(withContext(params, ctx, key))
+	})
+}
+
+func (s *stripe) GetPortalConfiguration(ctx context.Context, id string, params *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error) {
+	return call(ctx, s, read, "GetPortalConfiguration", id, func(ctx context.Context, key *string) (*st.BillingPortalConfiguration, error) {
+		return s.api.BillingPortalConfigurations.Get(id, withContext(params, ctx, key))
+	})
+}
+
+func (s *stripe) UpdatePortalConfiguration(ctx context.Context, id string, params *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error) {
+	return call(ctx, s, write, "UpdatePortalConfiguration", id, func(ctx context.Context, key *string) (*st.BillingPortalConfiguration, error) {
+		return s.api.BillingPortalConfigurations.Update(id, withContext(params, ctx, key))
+	})
+}
+
+func (s *stripe) ListPortalConfigurations(ctx context.Context, params *st.BillingPortalConfigurationListParams) ([]*st.BillingPortalConfiguration, error) {
+	return call(ctx, s, read, "ListPortalConfigurations", "", func(ctx context.Context, _ *string) ([]*st.BillingPortalConfiguration, error) {
+		p := st.BillingPortalConfigurationListParams{}
+		if params != nil {
+			p = *params
+		}
+		p.Context = ctx
+
+		var configurations []*st.BillingPortalConfiguration
+		it := s.api.BillingPortalConfigurations.List(&p)
+		for it.Next() {
+			configurations = append(configurations, it.BillingPortalConfiguration())
+		}
+		return configurations, it.Err()
+	})
+}
+
+func (s *stripe) CreateSubscription(ctx context.Context, params *st.SubscriptionParams) (*st.Subscription, error) {
+	return call(ctx, s, create, "CreateSubscription", "", func(ctx context.Context, key *string) (*st.Subscription, error) {
+		return s.api.Subscriptions.New(withContext(params, ctx, key))
+	})
 }
 
 func (s *stripe) GetSubscription(ctx context.Context, id string, params *st.SubscriptionParams) (*st.Subscription, error) {
-	return subscription.Get(id, params)
+	return call(ctx, s, read, "GetSubscription", id, func(ctx context.Context, key *string) (*st.Subscription, error) {
+		return s.api.Subscriptions.Get(id, withContext(params, ctx, key))
+	})
 }
 
 func (s *stripe) ResumeSubscription(ctx context.Context, id string, params *st.SubscriptionResumeParams) (*st.Subscription, error) {
-	return subscription.Resume(id, params)
+	return call(ctx, s, write, "ResumeSubscription", id, func(ctx context.Context, key *string) (*st.Subscription, error) {
+		return s.api.Subscriptions.Resume(id, withContext(params, ctx, key))
+	})
 }
 
 func (s *stripe) CancelSubscription(ctx context.Context, id string, params *st.SubscriptionCancelParams) error {
-	if _, err := subscription.Cancel(id, params); err != nil {
-		return err
-	}
-	return nil
+	_, err := call(ctx, s, write, "CancelSubscription", id, func(ctx context.Context, key *string) (*st.Subscription, error) {
+		return s.api.Subscriptions.Cancel(id, withContext(params, ctx, key))
+	})
+	return err
 }
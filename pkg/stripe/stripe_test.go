@@ -0,0 +1,184 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	st "github.com/stripe/stripe-go/v80"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(cfg *Config) (IStripe, error) {
+	return New(cfg, tracenoop.NewTracerProvider(), metricnoop.NewMeterProvider())
+}
+
+func TestNewSendsConfiguredHeaders(t *testing.T) {
+	var header http.Header
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"cus_123","object":"customer"}`))
+	})
+
+	client, err := newTestClient(&Config{
+		APIKey:     "sk_test_key",
+		Account:    "acct_default",
+		APIVersion: "2024-06-20",
+		BackendURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.GetCustomer(context.Background(), "cus_123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != "cus_123" {
+		t.Errorf("unexpected customer %q", c.ID)
+	}
+	if header.Get("Authorization") != "Bearer sk_test_key" {
+		t.Errorf("unexpected authorization %q", header.Get("Authorization"))
+	}
+	if header.Get("Stripe-Account") != "acct_default" || header.Get("Stripe-Version") != "2024-06-20" {
+		t.Errorf("unexpected headers %v", header)
+	}
+
+	params := &st.CustomerParams{}
+	params.SetStripeAccount("acct_other")
+	if _, err := client.GetCustomer(context.Background(), "cus_123", params); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Stripe-Account") != "acct_other" {
+		t.Errorf("expected per-call account, got %q", header.Get("Stripe-Account"))
+	}
+}
+
+func TestNewRequiresAPIKey(t *testing.T) {
+	if _, err := newTestClient(&Config{}); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestModuleFailsWithoutAPIKey(t *testing.T) {
+	t.Setenv("STRIPE_SECRET_KEY", "")
+
+	if err := fx.New(Stripe, fx.Invoke(func(IStripe) {}), fx.NopLogger).Err(); err == nil {
+		t.Error("expected missing key error")
+	}
+}
+
+func TestCheckoutAndPortal(t *testing.T) {
+	var forms = map[string]url.Values{}
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		forms[r.Method+" "+r.URL.Path] = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/billing_portal/sessions":
+			w.Write([]byte(`{"id":"bps_1","object":"billing_portal.session","url":"https://billing.stripe.com/p/session/bps_1"}`))
+		case "/v1/checkout/sessions":
+			w.Write([]byte(`{"id":"cs_1","object":"checkout.session","mode":"` + r.PostForm.Get("mode") + `"}`))
+		case "/v1/billing_portal/configurations":
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`{"object":"list","has_more":false,"data":[{"id":"bpc_1","object":"billing_portal.configuration"}]}`))
+				return
+			}
+			w.Write([]byte(`{"id":"bpc_1","object":"billing_portal.configuration"}`))
+		case "/v1/billing_portal/configurations/bpc_1":
+			w.Write([]byte(`{"id":"bpc_1","object":"billing_portal.configuration","active":false}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	client, err := newTestClient(&Config{APIKey: "sk_test_key", BackendURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	session, err := client.CreateBillingSession(ctx, &st.BillingPortalSessionParams{
+		Customer:  st.String("cus_1"),
+		ReturnURL: st.String("https://example.com/account"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.URL == "" || forms["POST /v1/billing_portal/sessions"].Get("customer") != "cus_1" {
+		t.Errorf("unexpected billing session %+v %v", session, forms)
+	}
+
+	for _, mode := range []st.CheckoutSessionMode{st.CheckoutSessionModeSubscription, st.CheckoutSessionModePayment} {
+		cs, err := client.CreateCheckoutSession(ctx, &st.CheckoutSessionParams{
+			Mode:       st.String(string(mode)),
+			Customer:   st.String("cus_1"),
+			SuccessURL: st.String("https://example.com/success"),
+			LineItems: []*st.CheckoutSessionLineItemParams{
+				{Price: st.String("price_1"), Quantity: st.Int64(1)},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		form := forms["POST /v1/checkout/sessions"]
+		if cs.Mode != mode || form.Get("line_items[0][price]") != "price_1" {
+			t.Errorf("unexpected checkout session %+v %v", cs, form)
+		}
+	}
+
+	if _, err := client.CreatePortalConfiguration(ctx, &st.BillingPortalConfigurationParams{
+		BusinessProfile: &st.BillingPortalConfigurationBusinessProfileParams{
+			Headline: st.String("Manage your plan"),
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.UpdatePortalConfiguration(ctx, "bpc_1", &st.BillingPortalConfigurationParams{
+		Active: st.Bool(false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Active || forms["POST /v1/billing_portal/configurations/bpc_1"].Get("active") != "false" {
+		t.Errorf("unexpected update %+v", updated)
+	}
+	configurations, err := client.ListPortalConfigurations(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configurations) != 1 || configurations[0].ID != "bpc_1" {
+		t.Errorf("unexpected configurations %+v", configurations)
+	}
+}
+
+func TestCallsUseContext(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"cs_1","object":"checkout.session"}`))
+	})
+
+	client, err := newTestClient(&Config{APIKey: "sk_test_key", BackendURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.CreateCheckoutSession(ctx, &st.CheckoutSessionParams{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled context to stop the call, got %v", err)
+	}
+}
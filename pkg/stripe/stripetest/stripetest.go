@@ -0,0 +1,743 @@
+// Package stripetest provides an in-memory, stateful stripe.IStripe for
+// tests that must run without network access.
+//
+//	fake := stripetest.New(stripetest.WithWebhook(router, "/webhooks/stripe", secret))
+//	svc := billing.NewService(fake)
+//
+// Calls that change state emit the events Stripe would send, signed with
+// the webhook secret, to the configured handler.
+package stripetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/smallbiznis/go-lib/pkg/stripe"
+	st "github.com/stripe/stripe-go/v80"
+)
+
+var _ stripe.IStripe = (*Stripe)(nil)
+
+// Stripe is an in-memory stripe.IStripe. It is safe for concurrent use.
+type Stripe struct {
+	now     func() time.Time
+	webhook *webhookTarget
+
+	mu             sync.Mutex
+	seq            int
+	customers      map[string]*st.Customer
+	subscriptions  map[string]*st.Subscription
+	checkouts      map[string]*st.CheckoutSession
+	configurations map[string]*st.BillingPortalConfiguration
+	checkoutTrials map[string]*int64
+	idempotent     map[string]any
+
+	// creation order, for deterministic iteration
+	subscriptionIDs  []string
+	configurationIDs []string
+
+	events     []*st.Event
+	deliveries []Delivery
+}
+
+type Option func(*Stripe)
+
+// WithClock replaces time.Now for created timestamps and billing periods.
+func WithClock(now func() time.Time) Option {
+	return func(s *Stripe) {
+		s.now = now
+	}
+}
+
+func New(opts ...Option) *Stripe {
+	s := &Stripe{
+		now:            time.Now,
+		customers:      make(map[string]*st.Customer),
+		subscriptions:  make(map[string]*st.Subscription),
+		checkouts:      make(map[string]*st.CheckoutSession),
+		configurations: make(map[string]*st.BillingPortalConfiguration),
+		checkoutTrials: make(map[string]*int64),
+		idempotent:     make(map[string]any),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Stripe) id(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_test%d", prefix, s.seq)
+}
+
+// idempotent returns the object created earlier with the same idempotency
+// key, if any.
+func idempotent[T any](s *Stripe, p *st.Params) (T, bool) {
+	var zero T
+	if p.IdempotencyKey == nil {
+		return zero, false
+	}
+	v, ok := s.idempotent[*p.IdempotencyKey].(T)
+	return v, ok
+}
+
+func (s *Stripe) remember(p *st.Params, v any) {
+	if p.IdempotencyKey != nil {
+		s.idempotent[*p.IdempotencyKey] = v
+	}
+}
+
+func notFound(object, id string) error {
+	return &st.Error{
+		HTTPStatusCode: http.StatusNotFound,
+		Type:           st.ErrorTypeInvalidRequest,
+		Code:           st.ErrorCodeResourceMissing,
+		Param:          "id",
+		Msg:            fmt.Sprintf("No such %s: '%s'", object, id),
+	}
+}
+
+func invalid(param, msg string) error {
+	return &st.Error{
+		HTTPStatusCode: http.StatusBadRequest,
+		Type:           st.ErrorTypeInvalidRequest,
+		Param:          param,
+		Msg:            msg,
+	}
+}
+
+func (s *Stripe) CreateCustomer(ctx context.Context, params *st.CustomerParams) (*st.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = &st.CustomerParams{}
+	}
+
+	s.mu.Lock()
+	if c, ok := idempotent[*st.Customer](s, &params.Params); ok {
+		s.mu.Unlock()
+		return copyOf(c), nil
+	}
+
+	c := &st.Customer{
+		ID:          s.id("cus"),
+		Object:      "customer",
+		Created:     s.now().Unix(),
+		Email:       st.StringValue(params.Email),
+		Name:        st.StringValue(params.Name),
+		Phone:       st.StringValue(params.Phone),
+		Description: st.StringValue(params.Description),
+		Metadata:    maps.Clone(params.Metadata),
+	}
+	s.customers[c.ID] = c
+	s.remember(&params.Params, c)
+	events := []*st.Event{s.event(st.EventTypeCustomerCreated, c, nil)}
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return copyOf(c), nil
+}
+
+func (s *Stripe) GetCustomer(ctx context.Context, id string, _ *st.CustomerParams) (*st.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.customers[id]
+	if !ok {
+		return nil, notFound("customer", id)
+	}
+	return copyOf(c), nil
+}
+
+// DeleteCustomer deletes the customer and, like Stripe, cancels its
+// active subscriptions.
+func (s *Stripe) DeleteCustomer(ctx context.Context, id string, _ *st.CustomerParams) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	c, ok := s.customers[id]
+	if !ok || c.Deleted {
+		s.mu.Unlock()
+		return notFound("customer", id)
+	}
+
+	var events []*st.Event
+	for _, subID := range s.subscriptionIDs {
+		if sub := s.subscriptions[subID]; sub.Customer.ID == id && sub.Status != st.SubscriptionStatusCanceled {
+			events = append(events, s.cancel(sub)...)
+		}
+	}
+
+	*c = st.Customer{ID: c.ID, Object: "customer", Deleted: true}
+	events = append(events, s.event(st.EventTypeCustomerDeleted, c, nil))
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return nil
+}
+
+func (s *Stripe) CreateBillingSession(ctx context.Context, params *st.BillingPortalSessionParams) (*st.BillingPortalSession, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params == nil || params.Customer == nil {
+		return nil, invalid("customer", "Missing required param: customer.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.customers[*params.Customer]; !ok || c.Deleted {
+		return nil, notFound("customer", *params.Customer)
+	}
+
+	session := &st.BillingPortalSession{
+		Object:    "billing_portal.session",
+		Created:   s.now().Unix(),
+		Customer:  *params.Customer,
+		ReturnURL: st.StringValue(params.ReturnURL),
+	}
+	session.ID = s.id("bps")
+	session.URL = "https://billing.stripe.test/p/session/" + session.ID
+
+	if params.Configuration != nil {
+		cfg, ok := s.configurations[*params.Configuration]
+		if !ok {
+			return nil, notFound("billing_portal.configuration", *params.Configuration)
+		}
+		session.Configuration = copyOf(cfg)
+	}
+	return session, nil
+}
+
+func (s *Stripe) CreateSubscription(ctx context.Context, params *st.SubscriptionParams) (*st.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params == nil || params.Customer == nil {
+		return nil, invalid("customer", "Missing required param: customer.")
+	}
+	if len(params.Items) == 0 {
+		return nil, invalid("items", "Missing required param: items.")
+	}
+
+	s.mu.Lock()
+	if sub, ok := idempotent[*st.Subscription](s, &params.Params); ok {
+		s.mu.Unlock()
+		return copyOf(sub), nil
+	}
+	if c, ok := s.customers[*params.Customer]; !ok || c.Deleted {
+		s.mu.Unlock()
+		return nil, notFound("customer", *params.Customer)
+	}
+
+	sub := s.subscribe(*params.Customer, params.Items, params.TrialPeriodDays, params.Metadata)
+	if st.StringValue(params.PaymentBehavior) == "default_incomplete" && sub.Status == st.SubscriptionStatusActive {
+		sub.Status = st.SubscriptionStatusIncomplete
+	}
+	sub.CancelAtPeriodEnd = st.BoolValue(params.CancelAtPeriodEnd)
+	s.remember(&params.Params, sub)
+	events := []*st.Event{s.event(st.EventTypeCustomerSubscriptionCreated, sub, nil)}
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return copyOf(sub), nil
+}
+
+// subscribe creates an active or, with trialDays, trialing subscription
+// billed monthly.
+func (s *Stripe) subscribe(customer string, items []*st.SubscriptionItemsParams, trialDays *int64, metadata map[string]string) *st.Subscription {
+	now := s.now()
+	sub := &st.Subscription{
+		ID:                 s.id("sub"),
+		Object:             "subscription",
+		Customer:           &st.Customer{ID: customer},
+		Status:             st.SubscriptionStatusActive,
+		Created:            now.Unix(),
+		StartDate:          now.Unix(),
+		CurrentPeriodStart: now.Unix(),
+		CurrentPeriodEnd:   now.AddDate(0, 1, 0).Unix(),
+		Metadata:           maps.Clone(metadata),
+		Items:              &st.SubscriptionItemList{},
+	}
+
+	if days := st.Int64Value(trialDays); days > 0 {
+		end := now.AddDate(0, 0, int(days))
+		sub.Status = st.SubscriptionStatusTrialing
+		sub.TrialStart = now.Unix()
+		sub.TrialEnd = end.Unix()
+		sub.CurrentPeriodEnd = end.Unix()
+	}
+
+	for _, item := range items {
+		quantity := int64(1)
+		if item.Quantity != nil {
+			quantity = *item.Quantity
+		}
+		sub.Items.Data = append(sub.Items.Data, &st.SubscriptionItem{
+			ID:           s.id("si"),
+			Object:       "subscription_item",
+			Created:      now.Unix(),
+			Price:        &st.Price{ID: st.StringValue(item.Price), Object: "price"},
+			Quantity:     quantity,
+			Subscription: sub.ID,
+		})
+	}
+
+	s.subscriptions[sub.ID] = sub
+	s.subscriptionIDs = append(s.subscriptionIDs, sub.ID)
+	return sub
+}
+
+func (s *Stripe) GetSubscription(ctx context.Context, id string, _ *st.SubscriptionParams) (*st.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return nil, notFound("subscription", id)
+	}
+	return copyOf(sub), nil
+}
+
+// ResumeSubscription resumes a paused subscription. As with Stripe, other
+// statuses cannot be resumed.
+func (s *Stripe) ResumeSubscription(ctx context.Context, id string, _ *st.SubscriptionResumeParams) (*st.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, notFound("subscription", id)
+	}
+	if sub.Status != st.SubscriptionStatusPaused {
+		s.mu.Unlock()
+		return nil, invalid("subscription", fmt.Sprintf("Subscription %s is %s and cannot be resumed.", id, sub.Status))
+	}
+
+	events := s.transition(sub, st.SubscriptionStatusActive)
+	now := s.now()
+	sub.CurrentPeriodStart = now.Unix()
+	sub.CurrentPeriodEnd = now.AddDate(0, 1, 0).Unix()
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return copyOf(sub), nil
+}
+
+func (s *Stripe) CancelSubscription(ctx context.Context, id string, _ *st.SubscriptionCancelParams) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return notFound("subscription", id)
+	}
+	if sub.Status == st.SubscriptionStatusCanceled {
+		s.mu.Unlock()
+		return invalid("subscription", fmt.Sprintf("Subscription %s is already canceled.", id))
+	}
+	events := s.cancel(sub)
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return nil
+}
+
+func (s *Stripe) cancel(sub *st.Subscription) []*st.Event {
+	now := s.now().Unix()
+	sub.Status = st.SubscriptionStatusCanceled
+	sub.CanceledAt = now
+	sub.EndedAt = now
+	return []*st.Event{s.event(st.EventTypeCustomerSubscriptionDeleted, sub, nil)}
+}
+
+// transition changes the status of sub and returns the
+// customer.subscription.updated event Stripe would send.
+func (s *Stripe) transition(sub *st.Subscription, status st.SubscriptionStatus) []*st.Event {
+	previous := map[string]interface{}{"status": string(sub.Status)}
+	sub.Status = status
+	return []*st.Event{s.event(st.EventTypeCustomerSubscriptionUpdated, sub, previous)}
+}
+
+// SetSubscriptionStatus moves a subscription to status, e.g. paused or
+// unpaid, as Stripe's billing engine would.
+func (s *Stripe) SetSubscriptionStatus(id string, status st.SubscriptionStatus) error {
+	s.mu.Lock()
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return notFound("subscription", id)
+	}
+	if sub.Status == st.SubscriptionStatusCanceled {
+		s.mu.Unlock()
+		return invalid("subscription", fmt.Sprintf("Subscription %s is canceled.", id))
+	}
+
+	var events []*st.Event
+	if status == st.SubscriptionStatusCanceled {
+		events = s.cancel(sub)
+	} else if sub.Status != status {
+		events = s.transition(sub, status)
+	}
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return nil
+}
+
+// PayInvoice simulates a successful renewal: an invoice.paid event is sent
+// and a trialing, past due or unpaid subscription becomes active.
+func (s *Stripe) PayInvoice(subscriptionID string) (*st.Invoice, error) {
+	return s.invoice(subscriptionID, true)
+}
+
+// FailInvoice simulates a failed renewal: an invoice.payment_failed event
+// is sent and the subscription becomes past due.
+func (s *Stripe) FailInvoice(subscriptionID string) (*st.Invoice, error) {
+	return s.invoice(subscriptionID, false)
+}
+
+func (s *Stripe) invoice(subscriptionID string, paid bool) (*st.Invoice, error) {
+	s.mu.Lock()
+	sub, ok := s.subscriptions[subscriptionID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, notFound("subscription", subscriptionID)
+	}
+	if sub.Status == st.SubscriptionStatusCanceled {
+		s.mu.Unlock()
+		return nil, invalid("subscription", fmt.Sprintf("Subscription %s is canceled.", subscriptionID))
+	}
+
+	in := &st.Invoice{
+		ID:           s.id("in"),
+		Object:       "invoice",
+		Created:      s.now().Unix(),
+		Customer:     &st.Customer{ID: sub.Customer.ID},
+		Subscription: &st.Subscription{ID: sub.ID},
+		AttemptCount: 1,
+	}
+
+	var events []*st.Event
+	if paid {
+		in.Paid = true
+		in.Status = st.InvoiceStatusPaid
+		events = append(events, s.event(st.EventTypeInvoicePaid, in, nil))
+		if sub.Status != st.SubscriptionStatusActive {
+			events = append(events, s.transition(sub, st.SubscriptionStatusActive)...)
+		}
+	} else {
+		in.Status = st.InvoiceStatusOpen
+		events = append(events, s.event(st.EventTypeInvoicePaymentFailed, in, nil))
+		if sub.Status != st.SubscriptionStatusPastDue {
+			events = append(events, s.transition(sub, st.SubscriptionStatusPastDue)...)
+		}
+	}
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return in, nil
+}
+
+func (s *Stripe) CreateCheckoutSession(ctx context.Context, params *st.CheckoutSessionParams) (*st.CheckoutSession, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params == nil || params.Mode == nil {
+		return nil, invalid("mode", "Missing required param: mode.")
+	}
+	mode := st.CheckoutSessionMode(*params.Mode)
+	if mode != st.CheckoutSessionModePayment && mode != st.CheckoutSessionModeSubscription && mode != st.CheckoutSessionModeSetup {
+		return nil, invalid("mode", fmt.Sprintf("Invalid mode: %s", mode))
+	}
+	if mode != st.CheckoutSessionModeSetup && len(params.LineItems) == 0 {
+		return nil, invalid("line_items", "Missing required param: line_items.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cs, ok := idempotent[*st.CheckoutSession](s, &params.Params); ok {
+		return copyOf(cs), nil
+	}
+
+	now := s.now()
+	cs := &st.CheckoutSession{
+		ID:                s.id("cs"),
+		Object:            "checkout.session",
+		Mode:              mode,
+		Status:            st.CheckoutSessionStatusOpen,
+		PaymentStatus:     st.CheckoutSessionPaymentStatusUnpaid,
+		Created:           now.Unix(),
+		ExpiresAt:         now.Add(24 * time.Hour).Unix(),
+		SuccessURL:        st.StringValue(params.SuccessURL),
+		CancelURL:         st.StringValue(params.CancelURL),
+		ClientReferenceID: st.StringValue(params.ClientReferenceID),
+		Metadata:          maps.Clone(params.Metadata),
+		LineItems:         &st.LineItemList{},
+	}
+	cs.URL = "https://checkout.stripe.test/c/pay/" + cs.ID
+
+	if params.Customer != nil {
+		if c, ok := s.customers[*params.Customer]; !ok || c.Deleted {
+			return nil, notFound("customer", *params.Customer)
+		}
+		cs.Customer = &st.Customer{ID: *params.Customer}
+	}
+
+	for _, item := range params.LineItems {
+		cs.LineItems.Data = append(cs.LineItems.Data, &st.LineItem{
+			ID:       s.id("li"),
+			Object:   "item",
+			Price:    &st.Price{ID: st.StringValue(item.Price), Object: "price"},
+			Quantity: st.Int64Value(item.Quantity),
+		})
+	}
+
+	s.checkouts[cs.ID] = cs
+	s.remember(&params.Params, cs)
+	s.checkoutTrials[cs.ID] = trialDays(params)
+	return copyOf(cs), nil
+}
+
+func trialDays(params *st.CheckoutSessionParams) *int64 {
+	if params.SubscriptionData == nil {
+		return nil
+	}
+	return params.SubscriptionData.TrialPeriodDays
+}
+
+func (s *Stripe) GetCheckoutSession(ctx context.Context, id string, _ *st.CheckoutSessionParams) (*st.CheckoutSession, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs, ok := s.checkouts[id]
+	if !ok {
+		return nil, notFound("checkout.session", id)
+	}
+	return copyOf(cs), nil
+}
+
+func (s *Stripe) ExpireCheckoutSession(ctx context.Context, id string, _ *st.CheckoutSessionExpireParams) (*st.CheckoutSession, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cs, ok := s.checkouts[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, notFound("checkout.session", id)
+	}
+	if cs.Status != st.CheckoutSessionStatusOpen {
+		s.mu.Unlock()
+		return nil, invalid("session", fmt.Sprintf("Only open sessions can be expired, %s is %s.", id, cs.Status))
+	}
+	cs.Status = st.CheckoutSessionStatusExpired
+	events := []*st.Event{s.event(st.EventTypeCheckoutSessionExpired, cs, nil)}
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return copyOf(cs), nil
+}
+
+// CompleteCheckoutSession simulates the customer finishing checkout. A
+// customer is created when the session had none, subscription sessions
+// start a subscription and payment sessions are marked paid.
+func (s *Stripe) CompleteCheckoutSession(id string) (*st.CheckoutSession, error) {
+	s.mu.Lock()
+	cs, ok := s.checkouts[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, notFound("checkout.session", id)
+	}
+	if cs.Status != st.CheckoutSessionStatusOpen {
+		s.mu.Unlock()
+		return nil, invalid("session", fmt.Sprintf("Only open sessions can be completed, %s is %s.", id, cs.Status))
+	}
+
+	var events []*st.Event
+	if cs.Customer == nil {
+		c := &st.Customer{ID: s.id("cus"), Object: "customer", Created: s.now().Unix()}
+		s.customers[c.ID] = c
+		cs.Customer = &st.Customer{ID: c.ID}
+		events = append(events, s.event(st.EventTypeCustomerCreated, c, nil))
+	}
+
+	cs.Status = st.CheckoutSessionStatusComplete
+	switch cs.Mode {
+	case st.CheckoutSessionModeSubscription:
+		items := make([]*st.SubscriptionItemsParams, 0, len(cs.LineItems.Data))
+		for _, li := range cs.LineItems.Data {
+			items = append(items, &st.SubscriptionItemsParams{Price: st.String(li.Price.ID), Quantity: st.Int64(li.Quantity)})
+		}
+		sub := s.subscribe(cs.Customer.ID, items, s.checkoutTrials[cs.ID], nil)
+		cs.Subscription = &st.Subscription{ID: sub.ID}
+		cs.PaymentStatus = st.CheckoutSessionPaymentStatusPaid
+		if sub.Status == st.SubscriptionStatusTrialing {
+			cs.PaymentStatus = st.CheckoutSessionPaymentStatusNoPaymentRequired
+		}
+		events = append(events, s.event(st.EventTypeCustomerSubscriptionCreated, sub, nil))
+	case st.CheckoutSessionModePayment:
+		cs.PaymentStatus = st.CheckoutSessionPaymentStatusPaid
+	default:
+		cs.PaymentStatus = st.CheckoutSessionPaymentStatusNoPaymentRequired
+	}
+	events = append(events, s.event(st.EventTypeCheckoutSessionCompleted, cs, nil))
+	s.mu.Unlock()
+
+	s.deliver(events)
+	return copyOf(cs), nil
+}
+
+func (s *Stripe) CreatePortalConfiguration(ctx context.Context, params *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = &st.BillingPortalConfigurationParams{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().Unix()
+	cfg := &st.BillingPortalConfiguration{
+		ID:              s.id("bpc"),
+		Object:          "billing_portal.configuration",
+		Active:          true,
+		IsDefault:       len(s.configurations) == 0,
+		Created:         now,
+		Updated:         now,
+		BusinessProfile: &st.BillingPortalConfigurationBusinessProfile{},
+	}
+	applyConfiguration(cfg, params)
+
+	s.configurations[cfg.ID] = cfg
+	s.configurationIDs = append(s.configurationIDs, cfg.ID)
+	return copyOf(cfg), nil
+}
+
+func (s *Stripe) GetPortalConfiguration(ctx context.Context, id string, _ *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, ok := s.configurations[id]
+	if !ok {
+		return nil, notFound("billing_portal.configuration", id)
+	}
+	return copyOf(cfg), nil
+}
+
+func (s *Stripe) UpdatePortalConfiguration(ctx context.Context, id string, params *st.BillingPortalConfigurationParams) (*st.BillingPortalConfiguration, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, ok := s.configurations[id]
+	if !ok {
+		return nil, notFound("billing_portal.configuration", id)
+	}
+	if params != nil {
+		applyConfiguration(cfg, params)
+	}
+	cfg.Updated = s.now().Unix()
+	return copyOf(cfg), nil
+}
+
+func (s *Stripe) ListPortalConfigurations(ctx context.Context, params *st.BillingPortalConfigurationListParams) ([]*st.BillingPortalConfiguration, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// newest first, as Stripe lists them
+	configurations := make([]*st.BillingPortalConfiguration, 0, len(s.configurations))
+	for i := len(s.configurationIDs) - 1; i >= 0; i-- {
+		cfg := s.configurations[s.configurationIDs[i]]
+		if params != nil && params.Active != nil && cfg.Active != *params.Active {
+			continue
+		}
+		if params != nil && params.IsDefault != nil && cfg.IsDefault != *params.IsDefault {
+			continue
+		}
+		configurations = append(configurations, copyOf(cfg))
+	}
+	return configurations, nil
+}
+
+func applyConfiguration(cfg *st.BillingPortalConfiguration, params *st.BillingPortalConfigurationParams) {
+	if params.Active != nil {
+		cfg.Active = *params.Active
+	}
+	if params.DefaultReturnURL != nil {
+		cfg.DefaultReturnURL = *params.DefaultReturnURL
+	}
+	if bp := params.BusinessProfile; bp != nil {
+		if bp.Headline != nil {
+			cfg.BusinessProfile.Headline = *bp.Headline
+		}
+		if bp.PrivacyPolicyURL != nil {
+			cfg.BusinessProfile.PrivacyPolicyURL = *bp.PrivacyPolicyURL
+		}
+		if bp.TermsOfServiceURL != nil {
+			cfg.BusinessProfile.TermsOfServiceURL = *bp.TermsOfServiceURL
+		}
+	}
+	for k, v := range params.Metadata {
+		if cfg.Metadata == nil {
+			cfg.Metadata = make(map[string]string)
+		}
+		cfg.Metadata[k] = v
+	}
+}
+
+// copyOf returns a deep copy so callers cannot change the stored objects,
+// or see later changes to them, through the returned pointer.
+func copyOf[T any](v *T) *T {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("stripetest: copy %T: %v", v, err))
+	}
+	c := new(T)
+	if err := json.Unmarshal(b, c); err != nil {
+		panic(fmt.Sprintf("stripetest: copy %T: %v", v, err))
+	}
+	return c
+}
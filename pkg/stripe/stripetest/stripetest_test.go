@@ -0,0 +1,313 @@
+package stripetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/stripe"
+	st "github.com/stripe/stripe-go/v80"
+)
+
+const secret = "whsec_test"
+
+func TestCustomerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+
+	c, err := fake.CreateCustomer(ctx, &st.CustomerParams{Email: st.String("a@example.com")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fake.GetCustomer(ctx, c.ID, nil)
+	if err != nil || got.Email != "a@example.com" {
+		t.Fatalf("unexpected customer %+v: %v", got, err)
+	}
+
+	sub, err := fake.CreateSubscription(ctx, &st.SubscriptionParams{
+		Customer: st.String(c.ID),
+		Items:    []*st.SubscriptionItemsParams{{Price: st.String("price_pro")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fake.DeleteCustomer(ctx, c.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := fake.GetCustomer(ctx, c.ID, nil); !got.Deleted {
+		t.Error("expected deleted customer")
+	}
+	if got, _ := fake.GetSubscription(ctx, sub.ID, nil); got.Status != st.SubscriptionStatusCanceled {
+		t.Errorf("expected subscription to be canceled with its customer, got %s", got.Status)
+	}
+
+	var stripeErr *st.Error
+	if err := fake.DeleteCustomer(ctx, c.ID, nil); !errors.As(err, &stripeErr) || stripeErr.HTTPStatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %v", err)
+	}
+}
+
+func TestSubscriptionTransitions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := New(WithClock(func() time.Time { return now }))
+
+	c, _ := fake.CreateCustomer(ctx, nil)
+	sub, err := fake.CreateSubscription(ctx, &st.SubscriptionParams{
+		Customer:        st.String(c.ID),
+		Items:           []*st.SubscriptionItemsParams{{Price: st.String("price_pro"), Quantity: st.Int64(3)}},
+		TrialPeriodDays: st.Int64(14),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Status != st.SubscriptionStatusTrialing || sub.TrialEnd != now.AddDate(0, 0, 14).Unix() || sub.Items.Data[0].Quantity != 3 {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+
+	if _, err := fake.ResumeSubscription(ctx, sub.ID, nil); err == nil {
+		t.Error("expected trialing subscription not to be resumable")
+	}
+
+	if _, err := fake.FailInvoice(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := fake.GetSubscription(ctx, sub.ID, nil); got.Status != st.SubscriptionStatusPastDue {
+		t.Errorf("expected past_due, got %s", got.Status)
+	}
+
+	if err := fake.SetSubscriptionStatus(sub.ID, st.SubscriptionStatusPaused); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := fake.ResumeSubscription(ctx, sub.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Status != st.SubscriptionStatusActive {
+		t.Errorf("expected active, got %s", resumed.Status)
+	}
+
+	if err := fake.CancelSubscription(ctx, sub.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.CancelSubscription(ctx, sub.ID, nil); err == nil {
+		t.Error("expected second cancel to fail")
+	}
+	if _, err := fake.ResumeSubscription(ctx, sub.ID, nil); err == nil {
+		t.Error("expected canceled subscription not to be resumable")
+	}
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+
+	params := &st.CustomerParams{Email: st.String("a@example.com")}
+	params.SetIdempotencyKey("key-1")
+	first, _ := fake.CreateCustomer(ctx, params)
+	second, _ := fake.CreateCustomer(ctx, params)
+	if first.ID != second.ID || len(fake.Events()) != 1 {
+		t.Errorf("expected one customer, got %s and %s", first.ID, second.ID)
+	}
+}
+
+func TestBillingAndCheckoutSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	c, _ := fake.CreateCustomer(ctx, nil)
+
+	cfg, err := fake.CreatePortalConfiguration(ctx, &st.BillingPortalConfigurationParams{
+		BusinessProfile: &st.BillingPortalConfigurationBusinessProfileParams{Headline: st.String("Acme")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.UpdatePortalConfiguration(ctx, cfg.ID, &st.BillingPortalConfigurationParams{Active: st.Bool(false)}); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := fake.ListPortalConfigurations(ctx, &st.BillingPortalConfigurationListParams{Active: st.Bool(true)}); len(list) != 0 {
+		t.Errorf("expected no active configuration, got %d", len(list))
+	}
+
+	session, err := fake.CreateBillingSession(ctx, &st.BillingPortalSessionParams{
+		Customer:      st.String(c.ID),
+		Configuration: st.String(cfg.ID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.URL == "" || session.Configuration.BusinessProfile.Headline != "Acme" {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if _, err := fake.CreateBillingSession(ctx, &st.BillingPortalSessionParams{Customer: st.String("cus_missing")}); err == nil {
+		t.Error("expected missing customer error")
+	}
+
+	cs, err := fake.CreateCheckoutSession(ctx, &st.CheckoutSessionParams{
+		Mode:      st.String(string(st.CheckoutSessionModeSubscription)),
+		Customer:  st.String(c.ID),
+		LineItems: []*st.CheckoutSessionLineItemParams{{Price: st.String("price_pro"), Quantity: st.Int64(1)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed, err := fake.CompleteCheckoutSession(cs.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := fake.GetSubscription(ctx, completed.Subscription.ID, nil)
+	if err != nil || sub.Customer.ID != c.ID || sub.Status != st.SubscriptionStatusActive {
+		t.Errorf("unexpected subscription %+v: %v", sub, err)
+	}
+	if _, err := fake.ExpireCheckoutSession(ctx, cs.ID, nil); err == nil {
+		t.Error("expected completed session not to expire")
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := New().CreateCustomer(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	wh, err := stripe.NewWebhook(&stripe.WebhookConfig{Secret: secret, Tolerance: 5 * time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		statuses []st.SubscriptionStatus
+		failures int
+		fail     = true
+	)
+	wh.OnSubscriptionUpdated(func(_ context.Context, sub *st.Subscription) error {
+		statuses = append(statuses, sub.Status)
+		return nil
+	})
+	wh.OnInvoicePaymentFailed(func(context.Context, *st.Invoice) error {
+		failures++
+		if fail {
+			return errors.New("temporarily unavailable")
+		}
+		return nil
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks/stripe", wh.Handler())
+
+	fake := New(WithWebhook(router, "/webhooks/stripe", secret))
+	c, _ := fake.CreateCustomer(ctx, nil)
+	sub, _ := fake.CreateSubscription(ctx, &st.SubscriptionParams{
+		Customer: st.String(c.ID),
+		Items:    []*st.SubscriptionItemsParams{{Price: st.String("price_pro")}},
+	})
+
+	if _, err := fake.FailInvoice(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.PayInvoice(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 || statuses[0] != st.SubscriptionStatusPastDue || statuses[1] != st.SubscriptionStatusActive {
+		t.Errorf("unexpected subscription updates %v", statuses)
+	}
+
+	var failed *Delivery
+	for _, d := range fake.Deliveries() {
+		if d.Event.Type == st.EventTypeInvoicePaymentFailed {
+			failed = &d
+		} else if d.Status != http.StatusOK {
+			t.Errorf("unexpected status %d for %s", d.Status, d.Event.Type)
+		}
+	}
+	if failed == nil || failed.Status != http.StatusInternalServerError {
+		t.Fatalf("expected failed delivery, got %+v", failed)
+	}
+
+	fail = false
+	for i := 0; i < 2; i++ {
+		status, err := fake.Redeliver(failed.Event.ID)
+		if err != nil || status != http.StatusOK {
+			t.Fatalf("unexpected redelivery %d: %v", status, err)
+		}
+	}
+	if failures != 2 {
+		t.Errorf("expected the duplicate redelivery to be skipped, got %d handler calls", failures)
+	}
+}
+
+func TestWebhookDeliveryWithClock(t *testing.T) {
+	ctx := context.Background()
+
+	wh, err := stripe.NewWebhook(&stripe.WebhookConfig{Secret: secret, Tolerance: 5 * time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks/stripe", wh.Handler())
+
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := New(
+		WithClock(func() time.Time { return past }),
+		WithWebhook(router, "/webhooks/stripe", secret),
+	)
+	if _, err := fake.CreateCustomer(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := fake.Deliveries()
+	if len(deliveries) == 0 {
+		t.Fatal("expected a delivery")
+	}
+	for _, d := range deliveries {
+		if d.Status != http.StatusOK {
+			t.Errorf("unexpected status %d for %s", d.Status, d.Event.Type)
+		}
+		if d.Event.Created != past.Unix() {
+			t.Errorf("expected event to be created at the fake clock, got %d", d.Event.Created)
+		}
+	}
+}
+
+func TestStoredObjectsAreCopied(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+
+	metadata := map[string]string{"plan": "pro"}
+	c, err := fake.CreateCustomer(ctx, &st.CustomerParams{Metadata: metadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata["plan"] = "free"
+	c.Metadata["plan"] = "free"
+	c.Email = "changed@example.com"
+
+	got, err := fake.GetCustomer(ctx, c.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata["plan"] != "pro" || got.Email != "" {
+		t.Errorf("stored customer changed through an alias: %+v", got)
+	}
+
+	events := fake.Events()
+	events[0].Data.Raw = nil
+	if fake.Events()[0].Data.Raw == nil {
+		t.Error("stored event changed through Events")
+	}
+}
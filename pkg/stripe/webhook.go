@@ -0,0 +1,285 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/smallbiznis/go-lib/pkg/config"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	st "github.com/stripe/stripe-go/v80"
+	"github.com/stripe/stripe-go/v80/webhook"
+	"go.uber.org/fx"
+)
+
+var (
+	WebhookModule = fx.Module("stripe.webhook", fx.Options(
+		fx.Provide(
+			NewWebhookConfig,
+			provideWebhook,
+		),
+	))
+)
+
+// MaxWebhookBytes bounds the size of webhook payloads read by the handler.
+const MaxWebhookBytes = 1 << 20
+
+// WebhookConfig configures the Webhook built by NewWebhook.
+type WebhookConfig struct {
+	// Secret is the signing secret of the webhook endpoint, whsec_...
+	Secret string `env:"STRIPE_WEBHOOK_SECRET" required:"true" secret:"true"`
+	// Tolerance is how old a signature may be before it is rejected.
+	Tolerance time.Duration `env:"STRIPE_WEBHOOK_TOLERANCE" default:"5m" validate:"gt=0"`
+}
+
+// NewWebhookConfig reads the webhook configuration from the environment.
+func NewWebhookConfig() (*WebhookConfig, error) {
+	return config.Load[WebhookConfig]()
+}
+
+// EventStore remembers handled events so Stripe's retried and duplicated
+// deliveries are only dispatched once, even when they arrive concurrently.
+type EventStore interface {
+	// Claim atomically records the event as handled and reports whether
+	// this call claimed it. It returns false when the event was already
+	// claimed.
+	Claim(ctx context.Context, id string) (bool, error)
+	// Release drops the claim of an event whose handlers failed so the
+	// redelivery is dispatched again.
+	Release(ctx context.Context, id string) error
+}
+
+// EventHandler handles a verified event.
+type EventHandler func(context.Context, *st.Event) error
+
+// Webhook verifies and dispatches Stripe webhook events.
+//
+//	w.OnInvoicePaid(func(ctx context.Context, in *st.Invoice) error {
+//		return billing.MarkPaid(ctx, in.ID)
+//	})
+//	router.POST("/webhooks/stripe", w.Handler())
+type Webhook struct {
+	secret    string
+	tolerance time.Duration
+	store     EventStore
+
+	mu       sync.RWMutex
+	handlers map[st.EventType][]EventHandler
+}
+
+type webhookParams struct {
+	fx.In
+
+	Config *WebhookConfig
+	Store  EventStore `optional:"true"`
+}
+
+func provideWebhook(p webhookParams) (*Webhook, error) {
+	return NewWebhook(p.Config, p.Store)
+}
+
+// NewWebhook builds a Webhook for cfg. A nil store keeps processed event
+// ids in memory.
+func NewWebhook(cfg *WebhookConfig, store EventStore) (*Webhook, error) {
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("stripe: webhook secret is required")
+	}
+	if store == nil {
+		store = NewMemoryEventStore(72 * time.Hour)
+	}
+
+	return &Webhook{
+		secret:    cfg.Secret,
+		tolerance: cfg.Tolerance,
+		store:     store,
+		handlers:  make(map[st.EventType][]EventHandler),
+	}, nil
+}
+
+// On registers fn for events of type t. Handlers run in registration order
+// and the first error stops the dispatch.
+func (w *Webhook) On(t st.EventType, fn EventHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handlers[t] = append(w.handlers[t], fn)
+}
+
+// OnSubscriptionUpdated registers fn for customer.subscription.updated.
+func (w *Webhook) OnSubscriptionUpdated(fn func(context.Context, *st.Subscription) error) {
+	on(w, st.EventTypeCustomerSubscriptionUpdated, fn)
+}
+
+// OnInvoicePaid registers fn for invoice.paid.
+func (w *Webhook) OnInvoicePaid(fn func(context.Context, *st.Invoice) error) {
+	on(w, st.EventTypeInvoicePaid, fn)
+}
+
+// OnInvoicePaymentFailed registers fn for invoice.payment_failed.
+func (w *Webhook) OnInvoicePaymentFailed(fn func(context.Context, *st.Invoice) error) {
+	on(w, st.EventTypeInvoicePaymentFailed, fn)
+}
+
+// OnCustomerDeleted registers fn for customer.deleted.
+func (w *Webhook) OnCustomerDeleted(fn func(context.Context, *st.Customer) error) {
+	on(w, st.EventTypeCustomerDeleted, fn)
+}
+
+func on[T any](w *Webhook, t st.EventType, fn func(context.Context, *T) error) {
+	w.On(t, func(ctx context.Context, event *st.Event) error {
+		obj := new(T)
+		if err := json.Unmarshal(event.Data.Raw, obj); err != nil {
+			return fmt.Errorf("stripe: decode %s: %w", event.Type, err)
+		}
+		return fn(ctx, obj)
+	})
+}
+
+// Handler verifies the Stripe-Signature header of the request and
+// dispatches the event. It responds 400 to payloads that cannot be
+// verified, 200 to events that were handled, are duplicates or have no
+// handler, and an error status when a handler or the store fails so that
+// Stripe retries the delivery.
+func (w *Webhook) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abort(c, errors.New(http.StatusRequestEntityTooLarge, "PayloadTooLarge", "webhook payload is too large"))
+				return
+			}
+			abort(c, errors.BadRequest("InvalidPayload", err.Error()))
+			return
+		}
+
+		event, err := webhook.ConstructEventWithOptions(payload, c.GetHeader("Stripe-Signature"), w.secret, webhook.ConstructEventOptions{
+			Tolerance: w.tolerance,
+			// Events are rendered with the account's API version, which
+			// may differ from the one stripe-go is pinned to.
+			IgnoreAPIVersionMismatch: true,
+		})
+		if err != nil {
+			abort(c, errors.BadRequest("InvalidSignature", err.Error()))
+			return
+		}
+
+		if err := w.dispatch(ctx, &event); err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+func (w *Webhook) dispatch(ctx context.Context, event *st.Event) error {
+	w.mu.RLock()
+	handlers := w.handlers[event.Type]
+	w.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	claimed, err := w.store.Claim(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, eventKey{}, event)
+	for _, fn := range handlers {
+		if err := fn(ctx, event); err != nil {
+			// released even when the request was canceled, or the
+			// redelivery would be skipped as a duplicate
+			if rerr := w.store.Release(context.WithoutCancel(ctx), event.ID); rerr != nil {
+				return fmt.Errorf("%w (release %s: %v)", err, event.ID, rerr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+type eventKey struct{}
+
+// EventFromContext returns the event being dispatched to a handler.
+func EventFromContext(ctx context.Context) (*st.Event, bool) {
+	event, ok := ctx.Value(eventKey{}).(*st.Event)
+	return event, ok
+}
+
+func abort(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := errors.From(err); ok {
+		status = e.Status()
+	}
+	c.Error(err)
+	c.Status(status)
+	c.Abort()
+}
+
+// MemoryEventStore is an EventStore for a single instance. Ids are
+// forgotten after ttl, which should cover Stripe's retry window of three
+// days.
+type MemoryEventStore struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	swept time.Time
+}
+
+func NewMemoryEventStore(ttl time.Duration) *MemoryEventStore {
+	return &MemoryEventStore{
+		ttl:   ttl,
+		seen:  make(map[string]time.Time),
+		swept: time.Now(),
+	}
+}
+
+// Claim only looks at id's own claim. Expired ids are dropped from the map
+// at most once per ttl, so a claim doesn't walk every id under the lock.
+func (s *MemoryEventStore) Claim(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.swept) >= s.ttl {
+		s.sweep(now)
+	}
+	if at, ok := s.seen[id]; ok && now.Sub(at) < s.ttl {
+		return false, nil
+	}
+	s.seen[id] = now
+	return true, nil
+}
+
+// sweep drops expired ids. It must be called with s.mu held.
+func (s *MemoryEventStore) sweep(now time.Time) {
+	for k, at := range s.seen {
+		if now.Sub(at) >= s.ttl {
+			delete(s.seen, k)
+		}
+	}
+	s.swept = now
+}
+
+func (s *MemoryEventStore) Release(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.seen, id)
+	return nil
+}
@@ -0,0 +1,220 @@
+package stripe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	st "github.com/stripe/stripe-go/v80"
+	"github.com/stripe/stripe-go/v80/webhook"
+)
+
+const testSecret = "whsec_test"
+
+func newWebhookRouter(t *testing.T, w *Webhook) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/webhooks/stripe", w.Handler())
+	return r
+}
+
+func deliver(r http.Handler, payload []byte, secret string, at time.Time) *httptest.ResponseRecorder {
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: at,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(signed.Payload))
+	req.Header.Set("Stripe-Signature", signed.Header)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func eventPayload(id string, t st.EventType, object string) []byte {
+	return []byte(fmt.Sprintf(`{"id":%q,"object":"event","type":%q,"api_version":%q,"data":{"object":%s}}`, id, t, st.APIVersion, object))
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	w, err := NewWebhook(&WebhookConfig{Secret: testSecret, Tolerance: 5 * time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	w.OnSubscriptionUpdated(func(ctx context.Context, sub *st.Subscription) error {
+		event, _ := EventFromContext(ctx)
+		got = append(got, event.ID+":"+sub.ID+":"+string(sub.Status))
+		return nil
+	})
+	w.OnCustomerDeleted(func(_ context.Context, c *st.Customer) error {
+		got = append(got, c.ID)
+		return nil
+	})
+	r := newWebhookRouter(t, w)
+
+	payload := eventPayload("evt_1", st.EventTypeCustomerSubscriptionUpdated, `{"id":"sub_1","object":"subscription","status":"past_due"}`)
+	for i := 0; i < 2; i++ {
+		if rec := deliver(r, payload, testSecret, time.Now()); rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	payload = eventPayload("evt_2", st.EventTypeCustomerDeleted, `{"id":"cus_1","object":"customer"}`)
+	if rec := deliver(r, payload, testSecret, time.Now()); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+
+	payload = eventPayload("evt_3", st.EventTypeChargeSucceeded, `{"id":"ch_1","object":"charge"}`)
+	if rec := deliver(r, payload, testSecret, time.Now()); rec.Code != http.StatusOK {
+		t.Fatalf("expected unhandled events to be acknowledged, got %d", rec.Code)
+	}
+
+	if len(got) != 2 || got[0] != "evt_1:sub_1:past_due" || got[1] != "cus_1" {
+		t.Errorf("unexpected dispatch %v", got)
+	}
+}
+
+func TestWebhookRejectsInvalidSignature(t *testing.T) {
+	w, err := NewWebhook(&WebhookConfig{Secret: testSecret, Tolerance: 5 * time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	w.OnInvoicePaid(func(context.Context, *st.Invoice) error {
+		called = true
+		return nil
+	})
+	r := newWebhookRouter(t, w)
+	payload := eventPayload("evt_1", st.EventTypeInvoicePaid, `{"id":"in_1","object":"invoice"}`)
+
+	if rec := deliver(r, payload, "whsec_other", time.Now()); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for wrong secret, got %d", rec.Code)
+	}
+	if rec := deliver(r, payload, testSecret, time.Now().Add(-10*time.Minute)); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 outside tolerance, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without signature, got %d", rec.Code)
+	}
+
+	if called {
+		t.Error("handler called for unverified payload")
+	}
+}
+
+func TestWebhookHandlerFailureIsRetried(t *testing.T) {
+	w, err := NewWebhook(&WebhookConfig{Secret: testSecret, Tolerance: 5 * time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	w.OnInvoicePaymentFailed(func(_ context.Context, in *st.Invoice) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+	r := newWebhookRouter(t, w)
+	payload := eventPayload("evt_1", st.EventTypeInvoicePaymentFailed, `{"id":"in_1","object":"invoice"}`)
+
+	if rec := deliver(r, payload, testSecret, time.Now()); rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 on handler failure, got %d", rec.Code)
+	}
+	if rec := deliver(r, payload, testSecret, time.Now()); rec.Code != http.StatusOK {
+		t.Errorf("expected retry to succeed, got %d", rec.Code)
+	}
+	if rec := deliver(r, payload, testSecret, time.Now()); rec.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected duplicate to be skipped, got %d after %d calls", rec.Code, calls)
+	}
+}
+
+func TestWebhookConcurrentDuplicates(t *testing.T) {
+	w, err := NewWebhook(&WebhookConfig{Secret: testSecret, Tolerance: 5 * time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	w.OnInvoicePaid(func(_ context.Context, in *st.Invoice) error {
+		calls.Add(1)
+		<-release
+		return nil
+	})
+	r := newWebhookRouter(t, w)
+	payload := eventPayload("evt_1", st.EventTypeInvoicePaid, `{"id":"in_1","object":"invoice"}`)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- deliver(r, payload, testSecret, time.Now()).Code
+		}()
+	}
+
+	// let every delivery reach the store before the first one finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the event to be handled once, got %d", n)
+	}
+}
+
+func TestMemoryEventStoreExpires(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEventStore(20 * time.Millisecond)
+
+	if ok, _ := store.Claim(ctx, "evt_1"); !ok {
+		t.Fatal("expected first claim to succeed")
+	}
+	if ok, _ := store.Claim(ctx, "evt_1"); ok {
+		t.Fatal("expected duplicate claim to fail")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ := store.Claim(ctx, "evt_1"); !ok {
+		t.Error("expected claim to succeed after ttl")
+	}
+	if ok, _ := store.Claim(ctx, "evt_2"); !ok || len(store.seen) != 2 {
+		t.Errorf("unexpected claims %v", store.seen)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	store.Claim(ctx, "evt_3")
+	if _, ok := store.seen["evt_2"]; ok || len(store.seen) != 1 {
+		t.Errorf("expected expired ids to be swept, got %v", store.seen)
+	}
+}
+
+func TestNewWebhookRequiresSecret(t *testing.T) {
+	if _, err := NewWebhook(&WebhookConfig{}, nil); err == nil {
+		t.Error("expected missing secret error")
+	}
+}
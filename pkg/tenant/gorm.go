@@ -0,0 +1,171 @@
+package tenant
+
+import (
+	"reflect"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultColumn is the column holding the tenant id of scoped models.
+	DefaultColumn = "tenant_id"
+
+	skipKey = "tenant:skip"
+)
+
+// ErrTenantMissing is returned when a tenant-scoped model is used without a
+// tenant in the statement context.
+var ErrTenantMissing = errors.InternalServerError("TenantMissing", "tenant-scoped model used without a tenant in context")
+
+// Plugin scopes every model with a tenant column to the tenant of the
+// statement context. Queries, including Row, Rows and Scan, updates and
+// deletes are filtered by the tenant and creates and updates set it. Raw and
+// Exec SQL is not scoped and must filter by tenant itself.
+//
+//	db.Use(tenant.NewPlugin())
+//	db.WithContext(ctx).Find(&invoices)
+type Plugin struct {
+	Column string
+}
+
+var _ gorm.Plugin = new(Plugin)
+
+// NewPlugin scopes models by DefaultColumn.
+func NewPlugin() *Plugin {
+	return &Plugin{Column: DefaultColumn}
+}
+
+// CrossTenant disables tenant scoping for the statements built from db, for
+// admin operations spanning tenants.
+func CrossTenant(db *gorm.DB) *gorm.DB {
+	return db.Set(skipKey, true)
+}
+
+func (p *Plugin) Name() string {
+	return "tenant"
+}
+
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	if p.Column == "" {
+		p.Column = DefaultColumn
+	}
+
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("tenant:query", p.filter); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tenant:row", p.filter); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("tenant:create", p.assign); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenant:update", p.update); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("tenant:delete", p.delete)
+}
+
+// scoped returns the tenant column and id for tenant-scoped statements.
+func (p *Plugin) scoped(db *gorm.DB) (field *schema.Field, id string, ok bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, "", false
+	}
+	if skip, _ := db.Get(skipKey); skip == true {
+		return nil, "", false
+	}
+
+	field = db.Statement.Schema.LookUpField(p.Column)
+	if field == nil {
+		return nil, "", false
+	}
+
+	id, found := FromContext(db.Statement.Context)
+	if !found {
+		db.AddError(ErrTenantMissing)
+		return nil, "", false
+	}
+	return field, id, true
+}
+
+func (p *Plugin) filter(db *gorm.DB) {
+	if db.Statement.SQL.Len() > 0 {
+		// raw SQL, clauses are not applied
+		return
+	}
+	field, id, ok := p.scoped(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
+
+func (p *Plugin) assign(db *gorm.DB) {
+	field, id, ok := p.scoped(db)
+	if !ok {
+		return
+	}
+	db.Statement.SetColumn(field.DBName, id, true)
+}
+
+func (p *Plugin) update(db *gorm.DB) {
+	if !p.guard(db) {
+		return
+	}
+	p.filter(db)
+	p.assign(db)
+}
+
+func (p *Plugin) delete(db *gorm.DB) {
+	if !p.guard(db) {
+		return
+	}
+	p.filter(db)
+}
+
+// guard keeps gorm.ErrMissingWhereClause working: gorm only adds primary key
+// conditions after our tenant condition, which would otherwise turn an
+// unconditioned update or delete into a tenant wide one.
+func (p *Plugin) guard(db *gorm.DB) bool {
+	if db.Error != nil || db.AllowGlobalUpdate || db.Statement.SQL.Len() > 0 {
+		return true
+	}
+	if _, ok := db.Statement.Clauses["WHERE"]; ok {
+		return true
+	}
+	if hasPrimaryKey(db.Statement) {
+		return true
+	}
+	db.AddError(gorm.ErrMissingWhereClause)
+	return false
+}
+
+func hasPrimaryKey(stmt *gorm.Statement) bool {
+	if stmt.Schema == nil || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+
+	rv := reflect.Indirect(stmt.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return false
+		}
+		rv = reflect.Indirect(rv.Index(0))
+	case reflect.Struct:
+	default:
+		return false
+	}
+
+	for _, field := range stmt.Schema.PrimaryFields {
+		if _, zero := field.ValueOf(stmt.Context, rv); !zero {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/smallbiznis/go-lib/internal/testdb"
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type invoice struct {
+	ID       uint
+	TenantID string
+	Number   string
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(NewPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPluginScopesStatements(t *testing.T) {
+	db := newDryRunDB(t).WithContext(NewContext(context.Background(), "acme"))
+
+	stmt := db.Where("number = ?", "INV-1").Find(&[]invoice{}).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "`invoices`.`tenant_id` = ?") {
+		t.Errorf("expected tenant filter, got %s", sql)
+	}
+
+	inv := invoice{Number: "INV-2"}
+	if err := db.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.TenantID != "acme" {
+		t.Errorf("expected tenant to be set, got %q", inv.TenantID)
+	}
+
+	stmt = db.Model(&invoice{ID: 1}).Update("number", "INV-3").Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "`tenant_id`=?") || !strings.Contains(sql, "`invoices`.`tenant_id` = ?") {
+		t.Errorf("expected tenant assignment and filter, got %s", sql)
+	}
+}
+
+func TestPluginRequiresTenant(t *testing.T) {
+	db := newDryRunDB(t).WithContext(context.Background())
+
+	if err := db.Find(&[]invoice{}).Error; !errors.Is(err, ErrTenantMissing) {
+		t.Errorf("expected ErrTenantMissing, got %v", err)
+	}
+
+	stmt := CrossTenant(db).Find(&[]invoice{}).Statement
+	if err := stmt.Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stmt.SQL.String(), "tenant_id") {
+		t.Errorf("expected no tenant filter, got %s", stmt.SQL.String())
+	}
+}
+
+func TestPluginKeepsMissingWhereCheck(t *testing.T) {
+	db := newDryRunDB(t).WithContext(NewContext(context.Background(), "acme"))
+
+	if err := db.Model(&invoice{}).Update("number", "INV-4").Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("expected ErrMissingWhereClause, got %v", err)
+	}
+}
+
+func TestPluginScopesRowsAndScan(t *testing.T) {
+	db := testdb.New(t, &invoice{})
+	if err := db.Use(NewPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tid := range []string{"acme", "globex"} {
+		ctx := NewContext(context.Background(), tid)
+		if err := db.WithContext(ctx).Create(&invoice{Number: tid + "-1"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db = db.WithContext(NewContext(context.Background(), "acme"))
+
+	var numbers []string
+	if err := db.Model(&invoice{}).Select("number").Scan(&numbers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(numbers) != 1 || numbers[0] != "acme-1" {
+		t.Errorf("Scan: expected [acme-1], got %v", numbers)
+	}
+
+	rows, err := db.Model(&invoice{}).Select("number").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	numbers = numbers[:0]
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, number)
+	}
+	if len(numbers) != 1 || numbers[0] != "acme-1" {
+		t.Errorf("Rows: expected [acme-1], got %v", numbers)
+	}
+}
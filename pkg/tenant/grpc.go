@@ -0,0 +1,107 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataTenantID is the metadata key carrying the tenant id.
+const MetadataTenantID = "x-tenant-id"
+
+// UnaryServerInterceptor resolves the tenant of unary calls with resolvers,
+// tried in order. Without resolvers the :authority is used; pass
+// Header(MetadataTenantID) to trust the x-tenant-id metadata. Add it to
+// server.GrpcServerProvider with
+//
+//	fx.Decorate(func(opts []grpc.ServerOption) []grpc.ServerOption {
+//		return append(opts,
+//			grpc.ChainUnaryInterceptor(tenant.UnaryServerInterceptor()),
+//			grpc.ChainStreamInterceptor(tenant.StreamServerInterceptor()),
+//		)
+//	})
+func UnaryServerInterceptor(resolvers ...Resolver) grpc.UnaryServerInterceptor {
+	resolver := grpcResolver(resolvers)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := resolveIncoming(ctx, resolver, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor resolves the tenant of streaming calls with
+// resolvers, tried in order.
+func StreamServerInterceptor(resolvers ...Resolver) grpc.StreamServerInterceptor {
+	resolver := grpcResolver(resolvers)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := resolveIncoming(ss.Context(), resolver, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// UnaryClientInterceptor propagates the tenant in ctx as x-tenant-id metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor propagates the tenant in ctx as x-tenant-id metadata.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+func grpcResolver(resolvers []Resolver) Resolver {
+	if len(resolvers) > 0 {
+		return Chain(resolvers...)
+	}
+	return Default()
+}
+
+func resolveIncoming(ctx context.Context, resolver Resolver, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	header := make(http.Header, len(md))
+	for k, vs := range md {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = vs
+	}
+
+	host := header.Get(":authority")
+	if host == "" {
+		host = header.Get("Host")
+	}
+
+	id, err := Resolve(ctx, resolver, &Request{
+		Host:   host,
+		Path:   method,
+		Header: header,
+	})
+	if err != nil {
+		return ctx, err
+	}
+	return Bind(ctx, id), nil
+}
+
+func outgoing(ctx context.Context) context.Context {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(MetadataTenantID)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataTenantID, id)
+}
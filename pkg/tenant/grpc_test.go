@@ -0,0 +1,52 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(Header(HeaderTenantID), Subdomain("example.com"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/billing.v1.InvoiceService/GetInvoice"}
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{"metadata", metadata.Pairs(MetadataTenantID, "acme", ":authority", "other.example.com"), "acme"},
+		{"authority", metadata.Pairs(":authority", "acme.example.com:443"), "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			_, err := interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				if id, _ := FromContext(ctx); id != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, id)
+				}
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	ctx := NewContext(context.Background(), "acme")
+	err := UnaryClientInterceptor()(ctx, "/m", nil, nil, nil, func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if got := md.Get(MetadataTenantID); len(got) != 1 || got[0] != "acme" {
+			t.Errorf("unexpected metadata %v", md)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
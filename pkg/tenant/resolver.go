@@ -0,0 +1,148 @@
+package tenant
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+)
+
+// Resolver finds the tenant of a request. It returns an empty id when the
+// request doesn't carry the tenant the way the resolver expects.
+type Resolver interface {
+	Resolve(ctx context.Context, r *Request) (string, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(ctx context.Context, r *Request) (string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, r *Request) (string, error) {
+	return f(ctx, r)
+}
+
+// TenantStore maps custom domains to tenant ids.
+type TenantStore interface {
+	// LookupDomain returns ErrTenantNotFound for unknown domains.
+	LookupDomain(ctx context.Context, domain string) (string, error)
+}
+
+// Chain tries resolvers in order and returns the first tenant found. A
+// resolver failing with ErrTenantNotFound passes on to the next one; the
+// error is returned only when no other resolver finds a tenant.
+func Chain(resolvers ...Resolver) Resolver {
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		var notFound error
+		for _, resolver := range resolvers {
+			id, err := resolver.Resolve(ctx, r)
+			if errors.Is(err, ErrTenantNotFound) {
+				notFound = err
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			if id != "" {
+				return id, nil
+			}
+		}
+		return "", notFound
+	})
+}
+
+// Default resolves the tenant to the request host. Headers are client
+// controlled, so resolving from X-Tenant-ID has to be opted into with
+// Header(HeaderTenantID).
+func Default() Resolver {
+	return Host()
+}
+
+// HeaderTenantID is the header carrying the tenant id.
+const HeaderTenantID = "X-Tenant-ID"
+
+// Header resolves the tenant from the named header.
+func Header(name string) Resolver {
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		return strings.TrimSpace(r.Header.Get(name)), nil
+	})
+}
+
+// Host resolves the tenant to the request host name.
+func Host() Resolver {
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		return r.Hostname(), nil
+	})
+}
+
+// Subdomain resolves acme.example.com to acme for the base domain
+// example.com. Nested subdomains are not resolved.
+func Subdomain(baseDomain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		host := r.Hostname()
+		if !strings.HasSuffix(host, suffix) {
+			return "", nil
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if sub == "" || strings.Contains(sub, ".") {
+			return "", nil
+		}
+		return sub, nil
+	})
+}
+
+// PathPrefix resolves /t/acme/invoices to acme for the prefix /t/.
+func PathPrefix(prefix string) Resolver {
+	prefix = "/" + strings.Trim(prefix, "/") + "/"
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		if !strings.HasPrefix(r.Path, prefix) {
+			return "", nil
+		}
+		id, _, _ := strings.Cut(strings.TrimPrefix(r.Path, prefix), "/")
+		return id, nil
+	})
+}
+
+// JWTClaim resolves the tenant from a claim of the bearer token in the
+// Authorization header. The token signature is not verified, the
+// authentication layer is expected to have done so.
+func JWTClaim(claim string) Resolver {
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return "", nil
+		}
+
+		parts := strings.Split(strings.TrimSpace(token), ".")
+		if len(parts) != 3 {
+			return "", nil
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return "", nil
+		}
+
+		claims := make(map[string]any)
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return "", nil
+		}
+
+		switch v := claims[claim].(type) {
+		case string:
+			return v, nil
+		case float64:
+			return fmt.Sprint(int64(v)), nil
+		}
+		return "", nil
+	})
+}
+
+// CustomDomain resolves the tenant by looking up the request host in store.
+func CustomDomain(store TenantStore) Resolver {
+	return ResolverFunc(func(ctx context.Context, r *Request) (string, error) {
+		return store.LookupDomain(ctx, r.Hostname())
+	})
+}
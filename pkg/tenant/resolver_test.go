@@ -0,0 +1,71 @@
+package tenant
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+)
+
+type domainStore map[string]string
+
+func (s domainStore) LookupDomain(ctx context.Context, domain string) (string, error) {
+	if id, ok := s[domain]; ok {
+		return id, nil
+	}
+	return "", ErrTenantNotFound
+}
+
+func TestResolvers(t *testing.T) {
+	token := "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"tid":"acme"}`)) + ".sig"
+
+	tests := []struct {
+		name     string
+		resolver Resolver
+		req      Request
+		want     string
+	}{
+		{"subdomain", Subdomain("example.com"), Request{Host: "acme.example.com:8080"}, "acme"},
+		{"nested subdomain", Subdomain("example.com"), Request{Host: "a.b.example.com"}, ""},
+		{"header", Header(HeaderTenantID), Request{Header: http.Header{"X-Tenant-Id": {"acme"}}}, "acme"},
+		{"path prefix", PathPrefix("/t"), Request{Path: "/t/acme/invoices"}, "acme"},
+		{"jwt claim", JWTClaim("tid"), Request{Header: http.Header{"Authorization": {"Bearer " + token}}}, "acme"},
+		{"custom domain", CustomDomain(domainStore{"billing.acme.io": "acme"}), Request{Host: "billing.acme.io"}, "acme"},
+		{"chain", Chain(Header(HeaderTenantID), Subdomain("example.com")), Request{Host: "acme.example.com", Header: http.Header{}}, "acme"},
+		{"chain skips unknown domain", Chain(CustomDomain(domainStore{}), Header(HeaderTenantID)), Request{Host: "unknown.io", Header: http.Header{"X-Tenant-Id": {"acme"}}}, "acme"},
+		{"default ignores header", Default(), Request{Host: "acme.io", Header: http.Header{"X-Tenant-Id": {"other"}}}, "acme.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.resolver.Resolve(context.Background(), &tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveUnresolved(t *testing.T) {
+	_, err := Resolve(context.Background(), Subdomain("example.com"), &Request{Host: "localhost"})
+	if !errors.Is(err, errors.ErrBadRequest) {
+		t.Errorf("expected bad request, got %v", err)
+	}
+
+	_, err = Resolve(context.Background(), CustomDomain(domainStore{}), &Request{Host: "unknown.io"})
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestChainNotFound(t *testing.T) {
+	_, err := Resolve(context.Background(), Chain(CustomDomain(domainStore{}), Subdomain("example.com")), &Request{Host: "unknown.io"})
+	if !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("expected tenant not found, got %v", err)
+	}
+}
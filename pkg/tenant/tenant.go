@@ -0,0 +1,80 @@
+package tenant
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/smallbiznis/go-lib/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttributeKey is the span attribute and baggage member carrying the tenant id.
+const AttributeKey = "tenant.id"
+
+var (
+	// ErrTenantRequired is returned when no resolver could find a tenant.
+	ErrTenantRequired = errors.BadRequest("TenantRequired", "tenant could not be resolved from the request")
+	// ErrTenantNotFound is returned by a TenantStore for unknown domains.
+	ErrTenantNotFound = errors.NotFound("TenantNotFound", "tenant not found")
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the tenant id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Bind stores the tenant id in ctx, records it on the active span and adds
+// it to the OpenTelemetry baggage so it reaches downstream services.
+func Bind(ctx context.Context, id string) context.Context {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String(AttributeKey, id))
+
+	if member, err := baggage.NewMemberRaw(AttributeKey, id); err == nil {
+		if bag, err := baggage.FromContext(ctx).SetMember(member); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, bag)
+		}
+	}
+
+	return NewContext(ctx, id)
+}
+
+// FromContext returns the tenant id stored in ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Request holds the parts of an incoming HTTP or gRPC request resolvers
+// inspect.
+type Request struct {
+	Host   string
+	Path   string
+	Header http.Header
+}
+
+// Hostname returns the request host without its port.
+func (r *Request) Hostname() string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// Resolve runs resolver against r and fails with ErrTenantRequired when no
+// tenant was found.
+func Resolve(ctx context.Context, resolver Resolver, r *Request) (string, error) {
+	id, err := resolver.Resolve(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", ErrTenantRequired
+	}
+	return id, nil
+}
@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type ginValidator struct {
+	validate *validator.Validate
+}
+
+var _ binding.StructValidator = (*ginValidator)(nil)
+
+// NewBinding adapts v to gin's binding.StructValidator, so c.ShouldBind and
+// friends check the validate tags and report the json paths and
+// translations registered on v.
+func NewBinding(v *validator.Validate) binding.StructValidator {
+	return &ginValidator{validate: v}
+}
+
+// RegisterBinding installs v as the validator used by gin's binding.
+func RegisterBinding(v *validator.Validate) {
+	binding.Validator = NewBinding(v)
+}
+
+// ValidateStruct validates structs and pointers to structs, and every
+// element of slices and arrays, the same way gin's default validator does.
+func (g *ginValidator) ValidateStruct(obj any) error {
+	if obj == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.Elem().Kind() != reflect.Struct {
+			return g.ValidateStruct(value.Elem().Interface())
+		}
+		return g.validate.Struct(obj)
+	case reflect.Struct:
+		return g.validate.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		errs := make(binding.SliceValidationError, 0)
+		for i := 0; i < value.Len(); i++ {
+			if err := g.ValidateStruct(value.Index(i).Interface()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	}
+	return nil
+}
+
+// Engine returns the underlying *validator.Validate.
+func (g *ginValidator) Engine() any {
+	return g.validate
+}
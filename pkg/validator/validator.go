@@ -11,8 +11,11 @@ import (
 	"go.uber.org/fx"
 )
 
+// Validator provides the *validator.Validate and installs it as gin's
+// binding validator.
 var Validator = fx.Module("validator", fx.Options(
 	fx.Provide(NewValidator),
+	fx.Invoke(RegisterBinding),
 ))
 
 var Translation = fx.Module("translation", fx.Options(